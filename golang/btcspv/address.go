@@ -0,0 +1,158 @@
+package btcspv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// AddressType is the output type that an address pays to
+type AddressType int
+
+// possible address types
+const (
+	AddressP2PKH          AddressType = 1
+	AddressP2SH           AddressType = 2
+	AddressP2WPKH         AddressType = 3
+	AddressP2WSH          AddressType = 4
+	AddressP2TR           AddressType = 5
+	AddressWitnessUnknown AddressType = 6
+)
+
+// String returns the name of the address type
+func (t AddressType) String() string {
+	switch t {
+	case AddressP2PKH:
+		return "P2PKH"
+	case AddressP2SH:
+		return "P2SH"
+	case AddressP2WPKH:
+		return "P2WPKH"
+	case AddressP2WSH:
+		return "P2WSH"
+	case AddressP2TR:
+		return "P2TR"
+	case AddressWitnessUnknown:
+		return "WitnessUnknown"
+	default:
+		return "Unknown"
+	}
+}
+
+// Address is a decoded address
+// Payload is the pubkey hash, script hash or witness program. WitnessVersion
+// is only meaningful for segwit addresses.
+type Address struct {
+	Type           AddressType `json:"type"`
+	WitnessVersion int         `json:"witness_version"`
+	Payload        HexBytes    `json:"payload"`
+}
+
+// DecodeAddress decodes a base58check or segwit address on net
+// Checksums are validated, and segwit addresses must use bech32 for witness
+// version 0 and bech32m for later versions, as in BIP350.
+func DecodeAddress(addr string, net *NetParams) (Address, error) {
+	// Also decode other networks' segwit addresses, to report a wrong HRP
+	_, _, _, err := bech32Decode(addr)
+	if err == nil || strings.HasPrefix(strings.ToLower(addr), net.Bech32HRP+"1") {
+		return decodeSegWit(addr, net)
+	}
+
+	payload, version, err := base58.CheckDecode(addr)
+	if err != nil {
+		return Address{}, fmt.Errorf("Invalid base58check address: %s", err)
+	}
+	if len(payload) != 20 {
+		return Address{}, fmt.Errorf("Base58check payload must be 20 bytes, got %d bytes", len(payload))
+	}
+
+	switch version {
+	case net.PubKeyHashAddrID:
+		return Address{Type: AddressP2PKH, Payload: payload}, nil
+	case net.ScriptHashAddrID:
+		return Address{Type: AddressP2SH, Payload: payload}, nil
+	default:
+		return Address{}, fmt.Errorf("Unknown address version byte 0x%02x for %s", version, net.Name)
+	}
+}
+
+// decodeSegWit decodes a bech32 or bech32m address
+func decodeSegWit(addr string, net *NetParams) (Address, error) {
+	hrp, data, constant, err := bech32Decode(addr)
+	if err != nil {
+		return Address{}, err
+	}
+	if hrp != net.Bech32HRP {
+		return Address{}, fmt.Errorf("Expected human-readable part %s, got %s", net.Bech32HRP, hrp)
+	}
+	if len(data) == 0 {
+		return Address{}, errors.New("Segwit address has no witness version")
+	}
+
+	version := int(data[0])
+	if version > 16 {
+		return Address{}, fmt.Errorf("Witness version must be 0 to 16, got %d", version)
+	}
+	if version == 0 && constant != bech32Const {
+		return Address{}, errors.New("Version 0 witness address must use bech32")
+	}
+	if version != 0 && constant != bech32mConst {
+		return Address{}, fmt.Errorf("Version %d witness address must use bech32m", version)
+	}
+
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return Address{}, fmt.Errorf("Invalid witness program: %s", err)
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return Address{}, fmt.Errorf("Witness program must be 2 to 40 bytes, got %d bytes", len(program))
+	}
+
+	a := Address{Type: AddressWitnessUnknown, WitnessVersion: version, Payload: program}
+	switch {
+	case version == 0 && len(program) == 20:
+		a.Type = AddressP2WPKH
+	case version == 0 && len(program) == 32:
+		a.Type = AddressP2WSH
+	case version == 0:
+		return Address{}, fmt.Errorf("Version 0 witness program must be 20 or 32 bytes, got %d bytes", len(program))
+	case version == 1 && len(program) == 32:
+		a.Type = AddressP2TR
+	}
+	return a, nil
+}
+
+// ScriptPubkey returns the output script that the address pays to
+func (a Address) ScriptPubkey() []byte {
+	switch a.Type {
+	case AddressP2PKH:
+		script := append([]byte{0x76, 0xa9, 0x14}, a.Payload...)
+		return append(script, 0x88, 0xac)
+	case AddressP2SH:
+		script := append([]byte{0xa9, 0x14}, a.Payload...)
+		return append(script, 0x87)
+	default:
+		// OP_0, or OP_1 to OP_16, then a push of the witness program
+		opcode := byte(0x00)
+		if a.WitnessVersion != 0 {
+			opcode = 0x50 + byte(a.WitnessVersion)
+		}
+		return append([]byte{opcode, byte(len(a.Payload))}, a.Payload...)
+	}
+}
+
+// MatchesOutput returns true if an output pays to the address
+// The output is formatted as returned by ExtractOutputAtIndex, with its
+// 8-byte value and length-prefixed script.
+func (a Address) MatchesOutput(output []byte) bool {
+	if len(output) < 9 {
+		return false
+	}
+	script := a.ScriptPubkey()
+	expected := append(encodeVarInt(uint64(len(script))), script...)
+	return bytes.Equal(output[8:], expected)
+}
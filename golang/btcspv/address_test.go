@@ -0,0 +1,83 @@
+package btcspv_test
+
+import (
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+func (suite *UtilsSuite) TestDecodeAddress() {
+	cases := []struct {
+		address      string
+		net          *btcspv.NetParams
+		addrType     btcspv.AddressType
+		scriptPubkey string
+	}{
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", &btcspv.MainNetParams, btcspv.AddressP2PKH, "76a91477bff20c60e522dfaa3350c39b030a5d004e839a88ac"},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", &btcspv.MainNetParams, btcspv.AddressP2SH, "a914b472a266d0bd89c13706a4132ccfb16f7c3b9fcb87"},
+		// BIP173 and BIP350 test vectors
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", &btcspv.MainNetParams, btcspv.AddressP2WPKH, "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", &btcspv.TestNet3Params, btcspv.AddressP2WSH, "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", &btcspv.MainNetParams, btcspv.AddressP2TR, "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", &btcspv.TestNet4Params, btcspv.AddressP2TR, "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", &btcspv.MainNetParams, btcspv.AddressWitnessUnknown, "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", &btcspv.MainNetParams, btcspv.AddressWitnessUnknown, "6002751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", &btcspv.MainNetParams, btcspv.AddressWitnessUnknown, "5210751e76e8199196d454941c45d1b3a323"},
+	}
+
+	for _, c := range cases {
+		addr, err := btcspv.DecodeAddress(c.address, c.net)
+		suite.Nil(err, c.address)
+		suite.Equal(c.addrType, addr.Type, c.address)
+		suite.Equal(decodeHex(c.scriptPubkey), addr.ScriptPubkey(), c.address)
+
+		// Outputs are matched by script, whatever their value
+		script := decodeHex(c.scriptPubkey)
+		output := append(decodeHex("a086010000000000"), byte(len(script)))
+		output = append(output, script...)
+		suite.True(addr.MatchesOutput(output), c.address)
+		output[len(output)-1] ^= 1
+		suite.False(addr.MatchesOutput(output), c.address)
+		suite.False(addr.MatchesOutput(output[:8]), c.address)
+	}
+
+	// Round trips through the encoders
+	addr, _ := btcspv.DecodeAddress("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", &btcspv.MainNetParams)
+	encoded, _ := btcspv.EncodeP2SH(addr.Payload, &btcspv.MainNetParams)
+	suite.Equal("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", encoded)
+	addr, _ = btcspv.DecodeAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", &btcspv.MainNetParams)
+	encoded, _ = btcspv.EncodeP2TR(addr.Payload, &btcspv.MainNetParams)
+	suite.Equal("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", encoded)
+}
+
+func (suite *UtilsSuite) TestDecodeAddressError() {
+	cases := []struct {
+		address string
+		err     string
+	}{
+		// Another network
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "Unknown address version byte 0x00 for testnet3"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "Expected human-readable part tb, got bc"},
+		// BIP350 invalid addresses
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq", "Bech32 string has mixed case"},
+		{"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47", "Version 0 witness address must use bech32"},
+		{"tb1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4", "Invalid bech32 character 'o'"},
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j", "Invalid witness program: invalid incomplete group"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k8", "Invalid bech32 checksum"},
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3", "Invalid base58check address: checksum error"},
+	}
+
+	for _, c := range cases {
+		_, err := btcspv.DecodeAddress(c.address, &btcspv.TestNet3Params)
+		suite.EqualError(err, c.err, c.address)
+	}
+
+	_, err := btcspv.DecodeAddress("bc1pw5dgrnzv", &btcspv.MainNetParams)
+	suite.EqualError(err, "Witness program must be 2 to 40 bytes, got 1 bytes")
+	_, err = btcspv.DecodeAddress("bc1gmk9yu", &btcspv.MainNetParams)
+	suite.EqualError(err, "Segwit address has no witness version")
+	_, err = btcspv.DecodeAddress("BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", &btcspv.MainNetParams)
+	suite.EqualError(err, "Witness version must be 0 to 16, got 17")
+	_, err = btcspv.DecodeAddress("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", &btcspv.MainNetParams)
+	suite.EqualError(err, "Version 1 witness address must use bech32m")
+	_, err = btcspv.DecodeAddress("BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P", &btcspv.MainNetParams)
+	suite.EqualError(err, "Version 0 witness program must be 20 or 32 bytes, got 16 bytes")
+}
@@ -0,0 +1,125 @@
+package btcspv
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// headerHashChunk is the number of headers a worker hashes at a time
+const headerHashChunk = 256
+
+// parallelFor calls f for every index below n on a pool of workers
+// Workers take the next index as they finish, so uneven work is balanced.
+// workers defaults to GOMAXPROCS if it is not positive.
+func parallelFor(n int, workers int, f func(i int)) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	next := int64(-1)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// HashHeaders computes the LE digest of each header in a chain of raw headers
+// Headers are hashed in parallel on a pool of workers, which defaults to
+// GOMAXPROCS if workers is not positive.
+func HashHeaders(headers []byte, workers int) ([]Hash256Digest, error) {
+	if len(headers)%80 != 0 {
+		return nil, NewSPVError(ErrCodeBadLength, "Header bytes not multiple of 80")
+	}
+
+	count := len(headers) / 80
+	digests := make([]Hash256Digest, count)
+	chunks := (count + headerHashChunk - 1) / headerHashChunk
+	parallelFor(chunks, workers, func(chunk int) {
+		end := (chunk + 1) * headerHashChunk
+		if end > count {
+			end = count
+		}
+		for i := chunk * headerHashChunk; i < end; i++ {
+			digests[i] = Hash256(headers[i*80 : (i+1)*80])
+		}
+	})
+	return digests, nil
+}
+
+// ValidateHeaderChainParallel checks validity of header chain in parallel
+// Workers hash chunks of headers, check each header's work and sum the
+// difficulty of their chunk. Only the links between headers are checked in
+// order, so the results, including errors, are those of ValidateHeaderChain.
+// workers defaults to GOMAXPROCS if it is not positive.
+func ValidateHeaderChainParallel(headers []byte, workers int, net *NetParams) (Uint256, error) {
+	if len(headers)%80 != 0 {
+		return Uint256{}, NewSPVError(ErrCodeBadLength, "Header bytes not multiple of 80")
+	}
+
+	count := len(headers) / 80
+	digests := make([]Hash256Digest, count)
+	workErrs := make([]error, count)
+	chunks := (count + headerHashChunk - 1) / headerHashChunk
+	chunkDifficulty := make([]Uint256, chunks)
+	parallelFor(chunks, workers, func(chunk int) {
+		end := (chunk + 1) * headerHashChunk
+		if end > count {
+			end = count
+		}
+		for i := chunk * headerHashChunk; i < end; i++ {
+			header, _ := NewRawHeader(headers[i*80 : (i+1)*80 : (i+1)*80])
+			digests[i] = Hash256(header[:])
+			difficulty, err := checkHeaderWork(header, digests[i], net)
+			if err != nil {
+				workErrs[i] = err
+				return
+			}
+			chunkDifficulty[chunk] = chunkDifficulty[chunk].Add(difficulty)
+		}
+	})
+
+	// A chunk stops at its first low-work header. Its error is returned
+	// unless an earlier link is broken, as in ValidateHeaderChain.
+	totalDifficulty := Uint256{}
+	for i := 0; i < count; i++ {
+		// The prevHash is at bytes 4 to 36 of the header
+		if i != 0 && !bytes.Equal(headers[i*80+4:i*80+36], digests[i-1][:]) {
+			return Uint256{}, NewSPVError(ErrCodeInvalidChain, "Header bytes not a valid chain")
+		}
+		if workErrs[i] != nil {
+			return Uint256{}, workErrs[i]
+		}
+		if i%headerHashChunk == 0 {
+			totalDifficulty = totalDifficulty.Add(chunkDifficulty[i/headerHashChunk])
+		}
+	}
+	return totalDifficulty, nil
+}
+
+// ValidateProofs validates many SPVProofs on a pool of workers
+// The error at each index is the error of Validate for the proof at that
+// index, or nil if the proof is valid. workers defaults to GOMAXPROCS if it is
+// not positive.
+func ValidateProofs(proofs []SPVProof, workers int) []error {
+	errs := make([]error, len(proofs))
+	parallelFor(len(proofs), workers, func(i int) {
+		_, errs[i] = proofs[i].Validate()
+	})
+	return errs
+}
@@ -0,0 +1,155 @@
+package btcspv_test
+
+import (
+	"testing"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// minedChain mines n linked regtest headers
+func minedChain(n int) []byte {
+	bits := btcspv.RegTestParams.PowLimitBits
+	headers := []byte{}
+	prev := Hash256Digest{}
+	for i := 0; i < n; i++ {
+		raw := mineHeader(prev, Hash256Digest{}, 1600000000+uint32(i)*600, bits)
+		prev = btcspv.Hash256(raw[:])
+		headers = append(headers, raw[:]...)
+	}
+	return headers
+}
+
+func (suite *UtilsSuite) TestHashHeaders() {
+	// Long enough to span several chunks
+	headers := []byte{}
+	for i := 0; i < 1000; i++ {
+		headers = append(headers, byte(i), byte(i>>8))
+		headers = append(headers, make([]byte, 78)...)
+	}
+
+	for _, workers := range []int{0, 1, 3, 16} {
+		digests, err := btcspv.HashHeaders(headers, workers)
+		suite.Nil(err)
+		suite.Equal(1000, len(digests))
+		for i := range digests {
+			suite.Equal(btcspv.Hash256(headers[i*80:(i+1)*80]), digests[i])
+		}
+	}
+
+	digests, err := btcspv.HashHeaders([]byte{}, 0)
+	suite.Nil(err)
+	suite.Equal(0, len(digests))
+
+	_, err = btcspv.HashHeaders(headers[:81], 0)
+	suite.EqualError(err, "Header bytes not multiple of 80")
+}
+
+func (suite *UtilsSuite) TestValidateHeaderChainParallel() {
+	fixture := suite.Fixtures.ValidateHeaderChain
+
+	for i := range fixture {
+		testCase := fixture[i]
+		expected := btcspv.NewUint256(testCase.Output)
+
+		for _, workers := range []int{0, 1, 4} {
+			actual, err := btcspv.ValidateHeaderChainParallel(testCase.Input, workers, &btcspv.MainNetParams)
+			suite.Nil(err)
+			suite.Equal(expected, actual)
+		}
+	}
+
+	fixtureError := suite.Fixtures.ValidateHeaderChainError
+
+	for i := range fixtureError {
+		testCase := fixtureError[i]
+		actual, err := btcspv.ValidateHeaderChainParallel(testCase.Input, 4, &btcspv.MainNetParams)
+		suite.Equal(actual, btcspv.Uint256{})
+		suite.EqualError(err, testCase.ErrorMessage)
+	}
+}
+
+func (suite *UtilsSuite) TestValidateHeaderChainParallelErrors() {
+	net := &btcspv.RegTestParams
+	headers := minedChain(600)
+
+	expected, err := btcspv.ValidateHeaderChain(headers, net)
+	suite.Nil(err)
+	actual, err := btcspv.ValidateHeaderChainParallel(headers, 3, net)
+	suite.Nil(err)
+	suite.Equal(expected, actual)
+
+	// breakLink breaks the link of header i, and lowWork makes it miss its
+	// target, as no nonce is tried
+	breakLink := func(h []byte, i int) { h[i*80+4] ^= 1 }
+	lowWork := func(h []byte, i int) { h[i*80+75] = 0x03 }
+
+	// The first error in chain order is returned, whichever chunk it is in
+	cases := []struct {
+		link    int
+		work    int
+		message string
+	}{
+		{100, 300, "Header bytes not a valid chain"},
+		{300, 100, "Header does not meet its own difficulty target"},
+		{500, 270, "Header does not meet its own difficulty target"},
+		{257, 257, "Header bytes not a valid chain"},
+	}
+	for _, c := range cases {
+		broken := append([]byte{}, headers...)
+		breakLink(broken, c.link)
+		lowWork(broken, c.work)
+
+		_, err := btcspv.ValidateHeaderChain(broken, net)
+		suite.EqualError(err, c.message)
+		for _, workers := range []int{1, 4} {
+			actual, err := btcspv.ValidateHeaderChainParallel(broken, workers, net)
+			suite.Equal(btcspv.Uint256{}, actual)
+			suite.EqualError(err, c.message)
+		}
+	}
+}
+
+func (suite *TypesSuite) TestValidateProofs() {
+	proofs := []SPVProof{}
+	expected := []string{}
+	for i := range suite.ValidProofs {
+		proofs = append(proofs, suite.ValidProofs[i])
+		expected = append(expected, "")
+	}
+	for i := range suite.Fixtures.InvalidProofs {
+		proofs = append(proofs, suite.Fixtures.InvalidProofs[i].Proof)
+		expected = append(expected, suite.Fixtures.InvalidProofs[i].Error)
+	}
+
+	for _, workers := range []int{0, 1, 2, 64} {
+		errs := btcspv.ValidateProofs(proofs, workers)
+		suite.Equal(len(proofs), len(errs))
+		for i := range errs {
+			if expected[i] == "" {
+				suite.Nil(errs[i])
+			} else {
+				suite.EqualError(errs[i], expected[i])
+			}
+		}
+	}
+
+	suite.Equal(0, len(btcspv.ValidateProofs([]SPVProof{}, 0)))
+}
+
+func BenchmarkValidateHeaderChain(b *testing.B) {
+	headers := minedChain(2016)
+	b.SetBytes(int64(len(headers)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		btcspv.ValidateHeaderChain(headers, &btcspv.RegTestParams)
+	}
+}
+
+func BenchmarkValidateHeaderChainParallel(b *testing.B) {
+	headers := minedChain(2016)
+	b.SetBytes(int64(len(headers)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		btcspv.ValidateHeaderChainParallel(headers, 0, &btcspv.RegTestParams)
+	}
+}
@@ -4,10 +4,9 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
-	"errors"
-	"math/big"
+	"math"
 
-	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/summa-tx/bitcoin-spv/golang/btcspv/script"
 	"golang.org/x/crypto/ripemd160"
 )
 
@@ -23,10 +22,14 @@ func BytesToUint(b []byte) uint {
 	return total
 }
 
-// BytesToBigUint converts a bytestring of up to 32 bytes to a cosmos sdk uint
-func BytesToBigUint(b []byte) sdk.Uint {
-	myInt := new(big.Int).SetBytes(b)
-	return sdk.NewUintFromBigInt(myInt)
+// BytesToBigUint converts a big-endian bytestring of up to 32 bytes to a Uint256
+// Longer bytestrings are truncated to their last 32 bytes
+func BytesToBigUint(b []byte) Uint256 {
+	if len(b) > 32 {
+		b = b[len(b)-32:]
+	}
+	res, _ := Uint256FromBytes(b)
+	return res
 }
 
 // DetermineVarIntDataLength extracts the payload length of a Bitcoin VarInt
@@ -45,12 +48,16 @@ func DetermineVarIntDataLength(flag uint8) uint8 {
 
 // ParseVarInt parses the length and value of a VarInt payload
 func ParseVarInt(b []byte) (uint64, uint64, error) {
+	if len(b) == 0 {
+		return 0, 0, NewSPVError(ErrCodeBadVarInt, "Read overrun during VarInt parsing")
+	}
+
 	dataLength := uint64(DetermineVarIntDataLength(b[0]))
 	if dataLength == 0 {
 		return 0, uint64(b[0]), nil
 	}
 	if uint64(len(b)) < 1+dataLength {
-		return 0, 0, errors.New("Read overrun during VarInt parsing")
+		return 0, 0, NewSPVError(ErrCodeBadVarInt, "Read overrun during VarInt parsing")
 	}
 
 	number := BytesToUint(ReverseEndianness(b[1 : 1+dataLength : 1+dataLength]))
@@ -79,10 +86,21 @@ func ReverseHash256Endianness(h Hash256Digest) Hash256Digest {
 }
 
 // LastBytes returns the last num in from a byte array
-func LastBytes(in []byte, num int) []byte {
+func LastBytes(in []byte, num int) ([]byte, error) {
+	if num < 0 || num > len(in) {
+		return []byte{}, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
 	out := make([]byte, num)
 	copy(out, in[len(in)-num:])
-	return out
+	return out, nil
+}
+
+// safeSlice returns b[start:end:end], or an error if the range is out of bounds
+func safeSlice(b []byte, start, end uint64) ([]byte, error) {
+	if start > end || end > uint64(len(b)) {
+		return []byte{}, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
+	return b[start:end:end], nil
 }
 
 // Hash160 takes a byte slice and returns a hashed byte slice.
@@ -116,11 +134,12 @@ func ExtractInputAtIndex(vin []byte, index uint) ([]byte, error) {
 		return []byte{}, err
 	}
 	if uint64(index) >= nIns {
-		return []byte{}, errors.New("Vin read overrun")
+		return []byte{}, NewSPVError(ErrCodeReadOverrun, "Vin read overrun")
 	}
 
-	var length uint
-	var offset = 1 + uint(dataLength)
+	// offset never exceeds len(vin), so slicing at offset is always safe
+	vinLength := uint64(len(vin))
+	var offset = 1 + dataLength
 	var remaining []byte
 
 	for i := uint(0); i < index; i++ {
@@ -128,38 +147,41 @@ func ExtractInputAtIndex(vin []byte, index uint) ([]byte, error) {
 
 		l, err := DetermineInputLength(remaining)
 		if err != nil {
-			return []byte{}, errors.New("Bad VarInt in scriptSig")
+			return []byte{}, NewSPVError(ErrCodeBadVarInt, "Bad VarInt in scriptSig")
 		}
-
-		length = uint(l)
-		if i != index {
-			offset += length
+		if l > vinLength-offset {
+			return []byte{}, NewSPVError(ErrCodeReadOverrun, "Read overrun when parsing vin")
 		}
+
+		offset += l
 	}
 
 	remaining = vin[offset:]
 	l, err := DetermineInputLength(remaining)
 	if err != nil {
-		return []byte{}, errors.New("Bad VarInt in scriptSig")
+		return []byte{}, NewSPVError(ErrCodeBadVarInt, "Bad VarInt in scriptSig")
 	}
-	if offset+uint(l) > uint(len(vin)) {
-		return []byte{}, errors.New("Read overrun when parsing vin")
+	if l > vinLength-offset {
+		return []byte{}, NewSPVError(ErrCodeReadOverrun, "Read overrun when parsing vin")
 	}
 
-	end := offset + uint(l)
+	end := offset + l
 	output := vin[offset:end:end]
 	return output, nil
 }
 
 // IsLegacyInput determines whether an input is legacy
-func IsLegacyInput(input []byte) bool {
-	return input[36] != 0
+func IsLegacyInput(input []byte) (bool, error) {
+	if len(input) < 37 {
+		return false, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
+	return input[36] != 0, nil
 }
 
 // ExtractScriptSigLen determines the length of a scriptSig in an input
 func ExtractScriptSigLen(input []byte) (uint64, uint64, error) {
 	if len(input) < 37 {
-		return 0, 0, errors.New("Read overrun")
+		return 0, 0, NewSPVError(ErrCodeReadOverrun, "Read overrun")
 	}
 
 	return ParseVarInt(input[36:])
@@ -171,6 +193,9 @@ func DetermineInputLength(input []byte) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if scriptSigLength > math.MaxUint64-41-dataLength {
+		return 0, NewSPVError(ErrCodeBadVarInt, "ScriptSig length overflows")
+	}
 
 	return 41 + dataLength + scriptSigLength, nil
 }
@@ -182,10 +207,13 @@ func ExtractSequenceLELegacy(input []byte) ([]byte, error) {
 	if err != nil {
 		return []byte{}, err
 	}
+	if scriptSigLength > uint64(len(input)) {
+		return []byte{}, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
 
 	offset := 36 + 1 + dataLength + scriptSigLength
 	end := offset + 4
-	return input[offset:end:end], nil
+	return safeSlice(input, offset, end)
 }
 
 // ExtractSequenceLegacy returns the integer sequence in from a tx input
@@ -203,42 +231,56 @@ func ExtractScriptSig(input []byte) ([]byte, error) {
 	if err != nil {
 		return []byte{}, err
 	}
+	if scriptSigLength > uint64(len(input)) {
+		return []byte{}, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
 	length := 1 + dataLength + scriptSigLength
 	end := 36 + length
-	return input[36:end:end], nil
+	return safeSlice(input, 36, end)
 }
 
 // ExtractSequenceLEWitness extracts the LE sequence bytes from a witness input
-func ExtractSequenceLEWitness(input []byte) []byte {
-	return input[37:41:41]
+func ExtractSequenceLEWitness(input []byte) ([]byte, error) {
+	return safeSlice(input, 37, 41)
 }
 
 // ExtractSequenceWitness extracts the sequence integer from a witness input
-func ExtractSequenceWitness(input []byte) uint32 {
-	return uint32(BytesToUint(ExtractSequenceLEWitness(input)))
+func ExtractSequenceWitness(input []byte) (uint32, error) {
+	seqBytes, err := ExtractSequenceLEWitness(input)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(BytesToUint(seqBytes)), nil
 }
 
 // ExtractOutpoint returns the outpoint from the in input in a tx
 // The outpoint is a 32 bit tx id with 4 byte index
-func ExtractOutpoint(input []byte) []byte {
-	return input[0:36:36]
+func ExtractOutpoint(input []byte) ([]byte, error) {
+	return safeSlice(input, 0, 36)
 }
 
 // ExtractInputTxIDLE returns the LE tx input index from the input in a tx
-func ExtractInputTxIDLE(input []byte) Hash256Digest {
-	res, _ := NewHash256Digest(input[0:32:32])
-	return res
+func ExtractInputTxIDLE(input []byte) (Hash256Digest, error) {
+	txid, err := safeSlice(input, 0, 32)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+	return NewHash256Digest(txid)
 }
 
 // ExtractTxIndexLE extracts the LE tx input index from the input in a tx
 // Returns the tx index as a little endian []byte
-func ExtractTxIndexLE(input []byte) []byte {
-	return input[32:36:36]
+func ExtractTxIndexLE(input []byte) ([]byte, error) {
+	return safeSlice(input, 32, 36)
 }
 
 // ExtractTxIndex extracts the tx input index from the input in a tx
-func ExtractTxIndex(input []byte) uint {
-	return BytesToUint(ReverseEndianness(ExtractTxIndexLE(input)))
+func ExtractTxIndex(input []byte) (uint, error) {
+	indexBytes, err := ExtractTxIndexLE(input)
+	if err != nil {
+		return 0, err
+	}
+	return BytesToUint(ReverseEndianness(indexBytes)), nil
 }
 
 //
@@ -248,13 +290,16 @@ func ExtractTxIndex(input []byte) uint {
 // DetermineOutputLength returns the length of an output
 func DetermineOutputLength(output []byte) (uint64, error) {
 	if len(output) < 9 {
-		return 0, errors.New("Read overrun")
+		return 0, NewSPVError(ErrCodeReadOverrun, "Read overrun")
 	}
 
 	dataLength, scriptPubkeyLength, err := ParseVarInt(output[8:])
 	if err != nil {
 		return 0, err
 	}
+	if scriptPubkeyLength > math.MaxUint64-9-dataLength {
+		return 0, NewSPVError(ErrCodeBadVarInt, "ScriptPubkey length overflows")
+	}
 
 	return (8 + 1 + dataLength + scriptPubkeyLength), nil
 }
@@ -266,105 +311,143 @@ func ExtractOutputAtIndex(vout []byte, index uint) ([]byte, error) {
 		return []byte{}, err
 	}
 	if uint64(index) >= nOuts {
-		return []byte{}, errors.New("Vout read overrun")
+		return []byte{}, NewSPVError(ErrCodeReadOverrun, "Vout read overrun")
 	}
 
-	var length uint
-	var offset = 1 + uint(dataLength)
+	// offset never exceeds len(vout), so slicing at offset is always safe
+	voutLength := uint64(len(vout))
+	var offset = 1 + dataLength
 	var remaining []byte
 
 	for i := uint(0); i < index; i++ {
 		remaining = vout[offset:]
 		l, err := DetermineOutputLength(remaining)
 		if err != nil {
-			return []byte{}, errors.New("Bad VarInt in scriptPubkey")
+			return []byte{}, NewSPVError(ErrCodeBadVarInt, "Bad VarInt in scriptPubkey")
 		}
-
-		length = uint(l)
-		if i != index {
-			offset += length
+		if l > voutLength-offset {
+			return []byte{}, NewSPVError(ErrCodeReadOverrun, "Read overrun when parsing vout")
 		}
+
+		offset += l
 	}
 
 	remaining = vout[offset:]
 	l, err := DetermineOutputLength(remaining)
 	if err != nil {
-		return []byte{}, errors.New("Bad VarInt in scriptPubkey")
+		return []byte{}, NewSPVError(ErrCodeBadVarInt, "Bad VarInt in scriptPubkey")
 	}
-	if offset+uint(l) > uint(len(vout)) {
-		return []byte{}, errors.New("Read overrun when parsing vout")
+	if l > voutLength-offset {
+		return []byte{}, NewSPVError(ErrCodeReadOverrun, "Read overrun when parsing vout")
 	}
 
-	end := offset + uint(l)
+	end := offset + l
 	output := vout[offset:end:end]
 	return output, nil
 }
 
 // ExtractValueLE extracts the value in from the output in a tx
 // Returns a little endian []byte of the output value
-func ExtractValueLE(output []byte) []byte {
-	return output[:8:8]
+func ExtractValueLE(output []byte) ([]byte, error) {
+	return safeSlice(output, 0, 8)
 }
 
 // ExtractValue extracts the value from the output in a tx
-func ExtractValue(output []byte) uint {
-	return BytesToUint(ReverseEndianness(ExtractValueLE(output)))
+func ExtractValue(output []byte) (uint, error) {
+	valueBytes, err := ExtractValueLE(output)
+	if err != nil {
+		return 0, err
+	}
+	return BytesToUint(ReverseEndianness(valueBytes)), nil
 }
 
-// ExtractOpReturnData returns the value from the output in a tx
-// Value is an 8byte little endian number
+// ExtractOpReturnData returns the data pushed by an OP_RETURN output
+// Returns the first element pushed after OP_RETURN, or empty data for a bare
+// OP_RETURN. Use ExtractOpReturnPushes for outputs that push more than one
+// element.
 func ExtractOpReturnData(output []byte) ([]byte, error) {
-	if output[9] != 0x6a {
-		return nil, errors.New("Not an op return")
+	pushes, err := ExtractOpReturnPushes(output)
+	if err != nil {
+		return nil, err
 	}
-
-	dataLength := int(output[10])
-	if dataLength+8+3 > len(output) {
-		return nil, errors.New("Malformatted data. Read overrun")
+	if len(pushes) == 0 {
+		return []byte{}, nil
 	}
-
-	end := 11 + dataLength
-	return output[11:end:end], nil
+	return pushes[0], nil
 }
 
-// ExtractHash extracts the hash from the output script
-// Returns the hash committed to by the pk_script
-func ExtractHash(output []byte) ([]byte, error) {
-	if uint(output[8] + 9) != uint(len(output)) {
-		return nil, errors.New("Reported length mismatch")
+// ExtractOpReturnPushes returns each element pushed by an OP_RETURN output
+// All push opcodes are supported, including OP_PUSHDATA1/2/4 and OP_0 to
+// OP_16, which push their number. The script must only push data after
+// OP_RETURN, and a bare OP_RETURN pushes no elements. OP_RESERVED counts as
+// a push, as in Core's IsPushOnly, but pushes no element.
+func ExtractOpReturnPushes(output []byte) ([][]byte, error) {
+	length, err := DetermineOutputLength(output)
+	if err != nil {
+		return nil, err
 	}
+	dataLength, _, _ := ParseVarInt(output[8:])
+	start := 9 + dataLength
 
-	tag := output[8:11:11]
-
-	/* Witness Case */
-	if output[9] == 0 {
-		length := uint(output[8]) - 2
-		if uint(output[10]) != length || (output[10] != 0x14 && output[10] != 0x20) {
-			return nil, errors.New("Maliciously formatted witness output")
-		}
-
-		end := 11 + length
-		return output[11:end:end], nil
+	if length > uint64(len(output)) {
+		return nil, NewSPVError(ErrCodeReadOverrun, "Malformatted data. Read overrun")
+	}
+	// Bytes after the script are not part of it
+	if start >= length || output[start] != script.OP_RETURN {
+		return nil, NewSPVError(ErrCodeMalformedOutput, "Not an op return")
 	}
 
-	/* P2PKH */
-	if bytes.Equal(tag, []byte{0x19, 0x76, 0xa9}) {
-		lastTwo := output[len(output)-2:]
-		if output[11] != 0x14 || !bytes.Equal(lastTwo, []byte{0x88, 0xac}) {
-			return nil, errors.New("Maliciously formatted p2pkh output")
+	pushes := [][]byte{}
+	t := script.NewTokenizer(output[start+1 : length])
+	for t.Next() {
+		if !t.Op().IsPush() {
+			return nil, NewSPVError(ErrCodeMalformedOutput, "Op return contains a non-push opcode")
+		}
+		element, ok := t.Op().Element()
+		if !ok {
+			continue
 		}
-		return output[12:32:32], nil
+		pushes = append(pushes, element[:len(element):len(element)])
+	}
+	if t.Err() != nil {
+		return nil, NewSPVError(ErrCodeReadOverrun, "Malformatted data. Read overrun")
 	}
+	return pushes, nil
+}
 
-	/* P2SH */
-	if bytes.Equal(tag, []byte{0x17, 0xa9, 0x14}) {
-		if output[len(output)-1] != 0x87 {
-			return nil, errors.New("Maliciously formatted p2sh output")
+// ExtractHash extracts the hash from the output script
+// Returns the hash committed to by the pk_script. For witness v1+ outputs,
+// returns the witness program, e.g. the taproot output key of P2TR.
+func ExtractHash(output []byte) ([]byte, error) {
+	if len(output) < 9 {
+		return nil, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
+	if uint(output[8])+9 != uint(len(output)) {
+		return nil, NewSPVError(ErrCodeBadLength, "Reported length mismatch")
+	}
+	if len(output) < 11 {
+		return nil, NewSPVError(ErrCodeMalformedOutput, "Nonstandard, OP_RETURN, or malformatted output")
+	}
+
+	pkScript := output[9:]
+	class, solutions := script.Classify(pkScript)
+	switch class {
+	case script.PubKeyHash, script.ScriptHash, script.WitnessV0KeyHash, script.WitnessV0ScriptHash, script.WitnessV1Taproot:
+		return solutions[0][:len(solutions[0]):len(solutions[0])], nil
+	case script.WitnessUnknown:
+		return solutions[1][:len(solutions[1]):len(solutions[1])], nil
+	case script.NonStandard:
+		// Report scripts that look like a template but don't match it
+		switch {
+		case pkScript[0] == script.OP_0 || (pkScript[0] >= script.OP_1 && pkScript[0] <= script.OP_16):
+			return nil, NewSPVError(ErrCodeMalformedOutput, "Maliciously formatted witness output")
+		case len(pkScript) == 25 && pkScript[0] == script.OP_DUP && pkScript[1] == script.OP_HASH160:
+			return nil, NewSPVError(ErrCodeMalformedOutput, "Maliciously formatted p2pkh output")
+		case len(pkScript) == 23 && pkScript[0] == script.OP_HASH160 && pkScript[1] == 20:
+			return nil, NewSPVError(ErrCodeMalformedOutput, "Maliciously formatted p2sh output")
 		}
-		return output[11:31:31], nil
 	}
-
-	return nil, errors.New("Nonstandard, OP_RETURN, or malformatted output")
+	return nil, NewSPVError(ErrCodeMalformedOutput, "Nonstandard, OP_RETURN, or malformatted output")
 }
 
 //
@@ -388,7 +471,7 @@ func ValidateVin(vin []byte) bool {
 		}
 
 		length, err := DetermineInputLength(vin[offset:])
-		if err != nil {
+		if err != nil || length > vinLength-offset {
 			return false
 		}
 		offset += length
@@ -410,13 +493,10 @@ func ValidateVout(vout []byte) bool {
 
 	for i := uint64(0); i < nOuts; i++ {
 		length, err := DetermineOutputLength(vout[offset:])
-		if err != nil {
+		if err != nil || length > voutLength-offset {
 			return false
 		}
 		offset += length
-		if offset > voutLength {
-			return false
-		}
 	}
 
 	return offset == voutLength
@@ -434,30 +514,90 @@ func ExtractMerkleRootLE(header RawHeader) Hash256Digest {
 }
 
 // ExtractTarget returns the target from a given block hedaer
-func ExtractTarget(header RawHeader) sdk.Uint {
-	// nBits encoding. 3 byte mantissa, 1 byte exponent
-	m := header[72:75:75]
-	e := sdk.NewInt(int64(header[75]))
+// Negative and overflowing nBits are returned as 0, which no header meets
+func ExtractTarget(header RawHeader) Uint256 {
+	target, negative, overflow := CompactToTarget(ExtractBits(header))
+	if negative || overflow {
+		return Uint256{}
+	}
+	return target
+}
 
-	// hacks
-	mantissa := BytesToBigUint(ReverseEndianness(m))
-	exponent := e.SubRaw(3)
+// ExtractBits returns the compact-encoded target (nBits) from a block header
+func ExtractBits(header RawHeader) uint32 {
+	return binary.LittleEndian.Uint32(header[72:76:76])
+}
+
+// CompactToTarget decodes nBits exactly like Bitcoin Core's arith_uint256::SetCompact
+// nBits is a 1 byte exponent, a sign bit and a 23 bit mantissa. Also returns
+// whether the encoding is negative, and whether it overflows 256 bits.
+// Consensus code must reject targets that are negative or overflow.
+func CompactToTarget(bits uint32) (Uint256, bool, bool) {
+	size := uint(bits >> 24)
+	word := uint64(bits & 0x007fffff)
+
+	var target Uint256
+	if size <= 3 {
+		word >>= 8 * (3 - size)
+		target = NewUint256(word)
+	} else {
+		target = NewUint256(word).Lsh(8 * (size - 3))
+	}
+
+	negative := word != 0 && bits&0x00800000 != 0
+	overflow := word != 0 && (size > 34 ||
+		(word > 0xff && size > 33) ||
+		(word > 0xffff && size > 32))
+
+	return target, negative, overflow
+}
+
+// TargetToCompact encodes a target as nBits exactly like Bitcoin Core's arith_uint256::GetCompact
+// The target is truncated to a 23 bit mantissa. Pass negative to set the sign
+// bit. Block headers never have a negative target.
+func TargetToCompact(target Uint256, negative bool) uint32 {
+	size := uint(target.BitLen()+7) / 8
+
+	var compact uint64
+	if size <= 3 {
+		compact = target.Uint64() << (8 * (3 - size))
+	} else {
+		compact = target.Rsh(8 * (size - 3)).Uint64()
+	}
 
-	// Have to convert to underlying big.Int as the sdk does not expose exponentiation
-	base := big.NewInt(256)
-	base.Exp(base, exponent.BigInt(), nil)
+	// 0x00800000 is the sign bit. If the mantissa would set it, move it up a byte
+	if compact&0x00800000 != 0 {
+		compact >>= 8
+		size++
+	}
 
-	exponentTerm := sdk.NewUintFromBigInt(base)
+	compact |= uint64(size) << 24
+	if negative && compact&0x007fffff != 0 {
+		compact |= 0x00800000
+	}
 
-	return mantissa.Mul(exponentTerm)
+	return uint32(compact)
 }
 
+// diffOneTarget is 0x1d00ffff as a 256 bit number
+var diffOneTarget = NewUint256(0xffff).Lsh(208)
+
 // CalculateDifficulty calculates difficulty from the difficulty 1 target and current target
-// Difficulty 1 is 0x1d00ffff on mainnet and testnet
+// Difficulty 1 is 0x1d00ffff on every built-in network, set in net.DiffOneTarget
 // Difficulty 1 is a 256 bit number encoded as a 3-byte mantissa and 1 byte exponent
-func CalculateDifficulty(target sdk.Uint) sdk.Uint {
-	diffOneTarget := sdk.NewUintFromString("0xffff0000000000000000000000000000000000000000000000000000")
-	return diffOneTarget.Quo(target)
+func CalculateDifficulty(target Uint256, net *NetParams) Uint256 {
+	return net.DiffOneTarget.Div(target)
+}
+
+// CalculateWork calculates the expected number of hashes needed to meet a target
+// This is Bitcoin Core's GetBlockProof, 2^256 / (target + 1). Unlike
+// difficulty, it is nonzero for targets easier than difficulty 1.
+func CalculateWork(target Uint256) Uint256 {
+	if target.IsZero() {
+		return Uint256{}
+	}
+	inverted := Uint256{^target[0], ^target[1], ^target[2], ^target[3]}
+	return inverted.Div(target.Add(NewUint256(1))).Add(NewUint256(1))
 }
 
 // ExtractPrevBlockHashLE returns the previous block's hash from a block header
@@ -481,8 +621,8 @@ func ExtractTimestamp(header RawHeader) uint {
 }
 
 // ExtractDifficulty calculates the difficulty of a header
-func ExtractDifficulty(header RawHeader) sdk.Uint {
-	return CalculateDifficulty(ExtractTarget(header))
+func ExtractDifficulty(header RawHeader, net *NetParams) Uint256 {
+	return CalculateDifficulty(ExtractTarget(header), net)
 }
 
 // Hash256MerkleStep concatenates and hashes two inputs for merkle proving
@@ -500,7 +640,7 @@ func VerifyHash256Merkle(proof []byte, index uint) bool {
 	idx := index
 	proofLength := len(proof)
 
-	if proofLength%32 != 0 {
+	if proofLength == 0 || proofLength%32 != 0 {
 		return false
 	}
 
@@ -535,26 +675,29 @@ func VerifyHash256Merkle(proof []byte, index uint) bool {
 }
 
 // RetargetAlgorithm performs Bitcoin consensus retargets
+// The result is not clamped to net.PowLimit.
 func RetargetAlgorithm(
-	previousTarget sdk.Uint,
+	previousTarget Uint256,
 	firstTimestamp uint,
-	secondTimestamp uint) sdk.Uint {
+	secondTimestamp uint,
+	net *NetParams) Uint256 {
 
-	retargetPeriod := sdk.NewUint(1209600)
-	lowerBound := retargetPeriod.QuoUint64(4)
-	upperBound := retargetPeriod.MulUint64(4)
+	retargetPeriod := net.TargetTimespan()
+	lowerBound := retargetPeriod / 4
+	upperBound := retargetPeriod * 4
 
-	first := sdk.NewUint(uint64(firstTimestamp))
-	second := sdk.NewUint(uint64(secondTimestamp))
-
-	elapsedTime := second.Sub(first)
+	// Timestamps may go backwards. Treat this as 0 elapsed time
+	var elapsedTime uint64
+	if secondTimestamp > firstTimestamp {
+		elapsedTime = uint64(secondTimestamp - firstTimestamp)
+	}
 
-	if elapsedTime.GT(upperBound) {
+	if elapsedTime > upperBound {
 		elapsedTime = upperBound
 	}
-	if elapsedTime.LT(lowerBound) {
+	if elapsedTime < lowerBound {
 		elapsedTime = lowerBound
 	}
 
-	return previousTarget.Mul(elapsedTime).Quo(retargetPeriod)
+	return previousTarget.Mul(NewUint256(elapsedTime)).Div(NewUint256(retargetPeriod))
 }
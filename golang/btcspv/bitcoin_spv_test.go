@@ -1,6 +1,7 @@
 package btcspv_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"log"
@@ -11,8 +12,6 @@ import (
 	"github.com/stretchr/testify/suite"
 	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
 	tutils "github.com/summa-tx/bitcoin-spv/golang/btcspv/test_utils"
-
-	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
 // Hash256Digest 32-byte double-sha2 digest
@@ -82,6 +81,8 @@ type TestCases struct {
 	EncodeP2PKH                  []tutils.EncodeP2PKHTC                `json:"encodeP2PKH"`
 	EncodeP2WSH                  []tutils.EncodeP2WSHTC                `json:"encodeP2WSH"`
 	EncodeP2WPKH                 []tutils.EncodeP2WPKHTC               `json:"encodeP2WPKH"`
+	InputTypes                   map[string]int                        `json:"INPUT_TYPES"`
+	OutputTypes                  []map[string]int                      `json:"OUTPUT_TYPES"`
 }
 
 type UtilsSuite struct {
@@ -131,8 +132,13 @@ func (suite *UtilsSuite) TestReverseHash256Endianness() {
 
 func (suite *UtilsSuite) TestLastBytes() {
 	testbytes := []byte{1, 2, 3, 4}
-	last := btcspv.LastBytes(testbytes, 1)
+	last, err := btcspv.LastBytes(testbytes, 1)
+	suite.Nil(err)
 	suite.Equal(last, []byte{4})
+
+	last, err = btcspv.LastBytes(testbytes, 5)
+	suite.Equal([]byte{}, last)
+	suite.EqualError(err, "Read overrun")
 }
 
 func (suite *UtilsSuite) TestHash160() {
@@ -172,7 +178,7 @@ func (suite *UtilsSuite) TestBytesToBigUint() {
 	hexString := "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
 	decoded := btcspv.DecodeIfHex(hexString)
 
-	expected := sdk.NewUintFromString(hexString)
+	expected := btcspv.Uint256{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)}
 	actual := btcspv.BytesToBigUint(decoded)
 
 	suite.Equal(expected, actual)
@@ -184,7 +190,8 @@ func (suite *UtilsSuite) TestExtractSequenceWitness() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual := btcspv.ExtractSequenceWitness(testCase.Input)
+		actual, err := btcspv.ExtractSequenceWitness(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -195,7 +202,8 @@ func (suite *UtilsSuite) TestExtractSequenceLEWitness() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := []byte(testCase.Output)
-		actual := btcspv.ExtractSequenceLEWitness(testCase.Input)
+		actual, err := btcspv.ExtractSequenceLEWitness(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -251,7 +259,8 @@ func (suite *UtilsSuite) TestExtractOutpoint() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := []byte(testCase.Output)
-		actual := btcspv.ExtractOutpoint(testCase.Input)
+		actual, err := btcspv.ExtractOutpoint(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -275,6 +284,22 @@ func (suite *UtilsSuite) TestExtractHash() {
 		suite.Nil(actual)
 		suite.EqualError(err, testCase.ErrorMessage)
 	}
+
+	// Witness v1+ outputs return their witness program
+	key := "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	actual, err := btcspv.ExtractHash(decodeHex("a086010000000000" + "22" + "5120" + key))
+	suite.Nil(err)
+	suite.Equal(decodeHex(key), actual)
+
+	actual, err = btcspv.ExtractHash(decodeHex("a086010000000000" + "04" + "6002751e"))
+	suite.Nil(err)
+	suite.Equal(decodeHex("751e"), actual)
+
+	// The push must match the script length, and be 2 to 40 bytes
+	_, err = btcspv.ExtractHash(decodeHex("a086010000000000" + "22" + "5121" + key))
+	suite.EqualError(err, "Maliciously formatted witness output")
+	_, err = btcspv.ExtractHash(decodeHex("a086010000000000" + "03" + "510175"))
+	suite.EqualError(err, "Maliciously formatted witness output")
 }
 
 func (suite *UtilsSuite) TestExtractValue() {
@@ -283,7 +308,8 @@ func (suite *UtilsSuite) TestExtractValue() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual := btcspv.ExtractValue(testCase.Input)
+		actual, err := btcspv.ExtractValue(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -294,7 +320,8 @@ func (suite *UtilsSuite) TestExtractValueLE() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := []byte(testCase.Output)
-		actual := btcspv.ExtractValueLE(testCase.Input)
+		actual, err := btcspv.ExtractValueLE(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -318,6 +345,61 @@ func (suite *UtilsSuite) TestExtractOpReturnData() {
 		suite.Nil(actual)
 		suite.EqualError(err, testCase.ErrorMessage)
 	}
+
+	// Data longer than 75 bytes is pushed with OP_PUSHDATA1
+	data := bytes.Repeat([]byte{0xab}, 80)
+	output := append(decodeHex("0000000000000000"+"53"+"6a4c50"), data...)
+	actual, err := btcspv.ExtractOpReturnData(output)
+	suite.Nil(err)
+	suite.Equal(data, actual)
+
+	_, err = btcspv.ExtractOpReturnData(decodeHex("0000000000000000" + "02" + "6a76"))
+	suite.EqualError(err, "Op return contains a non-push opcode")
+}
+
+func (suite *UtilsSuite) TestExtractOpReturnPushes() {
+	commitment := bytes.Repeat([]byte{0xcd}, 80)
+	long := bytes.Repeat([]byte{0xef}, 300)
+	script := append(decodeHex("6a"+"4c50"), commitment...)
+	script = append(script, decodeHex("0300010200"+"4f"+"60"+"4d2c01")...)
+	script = append(script, long...)
+	output := append(decodeHex("0000000000000000"+"fd8901"), script...)
+
+	actual, err := btcspv.ExtractOpReturnPushes(output)
+	suite.Nil(err)
+	suite.Equal([][]byte{commitment, {0x00, 0x01, 0x02}, {}, {0x81}, {0x10}, long}, actual)
+
+	// A bare OP_RETURN pushes nothing
+	actual, err = btcspv.ExtractOpReturnPushes(decodeHex("0000000000000000" + "01" + "6a"))
+	suite.Nil(err)
+	suite.Equal([][]byte{}, actual)
+
+	data, err := btcspv.ExtractOpReturnData(decodeHex("0000000000000000" + "01" + "6a"))
+	suite.Nil(err)
+	suite.Equal([]byte{}, data)
+
+	// Pushes must fit in the reported script length
+	_, err = btcspv.ExtractOpReturnPushes(decodeHex("0000000000000000" + "03" + "6a0201" + "02"))
+	suite.EqualError(err, "Malformatted data. Read overrun")
+	_, err = btcspv.ExtractOpReturnPushes(decodeHex("0000000000000000" + "03" + "6a4c"))
+	suite.EqualError(err, "Malformatted data. Read overrun")
+	_, err = btcspv.ExtractOpReturnPushes(decodeHex("0000000000000000" + "02" + "6a61"))
+	suite.EqualError(err, "Op return contains a non-push opcode")
+
+	// OP_RESERVED is a push, as in Core, but pushes nothing
+	actual, err = btcspv.ExtractOpReturnPushes(decodeHex("0000000000000000" + "04" + "6a500101"))
+	suite.Nil(err)
+	suite.Equal([][]byte{{0x01}}, actual)
+	_, err = btcspv.ExtractOpReturnPushes(decodeHex("0000000000000000" + "01" + "76"))
+	suite.EqualError(err, "Not an op return")
+
+	// An empty script followed by an OP_RETURN byte is not an op return
+	_, err = btcspv.ExtractOpReturnPushes(decodeHex("0000000000000000" + "00" + "6a"))
+	suite.EqualError(err, "Not an op return")
+	_, err = btcspv.ExtractOpReturnData(decodeHex("0000000000000000" + "00" + "6a"))
+	suite.EqualError(err, "Not an op return")
+	_, err = btcspv.ExtractOpReturnData(decodeHex("0000000000000000" + "00" + "6a0101"))
+	suite.EqualError(err, "Not an op return")
 }
 
 func (suite *UtilsSuite) TestExtractInputAtIndex() {
@@ -347,7 +429,8 @@ func (suite *UtilsSuite) TestIsLegacyInput() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual := btcspv.IsLegacyInput(testCase.Input)
+		actual, err := btcspv.IsLegacyInput(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -428,7 +511,8 @@ func (suite *UtilsSuite) TestExtractInputTxIDLE() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual := btcspv.ExtractInputTxIDLE(testCase.Input)
+		actual, err := btcspv.ExtractInputTxIDLE(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -439,7 +523,8 @@ func (suite *UtilsSuite) TestExtractTxIndexLE() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := []byte(testCase.Output)
-		actual := btcspv.ExtractTxIndexLE(testCase.Input)
+		actual, err := btcspv.ExtractTxIndexLE(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -450,7 +535,8 @@ func (suite *UtilsSuite) TestExtractTxIndex() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual := btcspv.ExtractTxIndex(testCase.Input)
+		actual, err := btcspv.ExtractTxIndex(testCase.Input)
+		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
 }
@@ -567,15 +653,10 @@ func (suite *UtilsSuite) TestRetargetAlgorithm() {
 		previousTarget := btcspv.ExtractTarget(testCaseSecond.Hex)
 		expectedNewTarget := btcspv.ExtractTarget(testCaseExpected.Hex)
 
-		actual := btcspv.RetargetAlgorithm((previousTarget), firstTimestamp, secondTimestamp)
+		actual := btcspv.RetargetAlgorithm((previousTarget), firstTimestamp, secondTimestamp, &btcspv.MainNetParams)
 
-		// dirty hacks. sdk.Uint doesn't give us easy access to the underlying
-		a, _ := actual.MarshalAmino()
-		e, _ := expectedNewTarget.MarshalAmino()
-		actualBI := new(big.Int)
-		actualBI.SetString(a, 0)
-		expectedBI := new(big.Int)
-		expectedBI.SetString(e, 0)
+		actualBI := actual.Big()
+		expectedBI := expectedNewTarget.Big()
 
 		res := new(big.Int)
 		res.And(actualBI, expectedBI)
@@ -584,13 +665,13 @@ func (suite *UtilsSuite) TestRetargetAlgorithm() {
 
 		// long
 		fakeSecond := firstTimestamp + 5*2016*10*60
-		longRes := btcspv.RetargetAlgorithm(previousTarget, firstTimestamp, fakeSecond)
-		suite.Equal(previousTarget.MulUint64(4), longRes)
+		longRes := btcspv.RetargetAlgorithm(previousTarget, firstTimestamp, fakeSecond, &btcspv.MainNetParams)
+		suite.Equal(previousTarget.Mul(btcspv.NewUint256(4)), longRes)
 
 		// short
 		fakeSecond = firstTimestamp + 2016*10*14
-		shortRes := btcspv.RetargetAlgorithm(previousTarget, firstTimestamp, fakeSecond)
-		suite.Equal(previousTarget.QuoUint64(4), shortRes)
+		shortRes := btcspv.RetargetAlgorithm(previousTarget, firstTimestamp, fakeSecond, &btcspv.MainNetParams)
+		suite.Equal(previousTarget.Div(btcspv.NewUint256(4)), shortRes)
 	}
 }
 
@@ -602,8 +683,8 @@ func (suite *UtilsSuite) TestExtractDifficulty() {
 		input := testCase.Input
 		for j := range input {
 			h := input[j]
-			actual := btcspv.ExtractDifficulty(h.Hex)
-			expected := sdk.NewUint(h.Difficulty)
+			actual := btcspv.ExtractDifficulty(h.Hex, &btcspv.MainNetParams)
+			expected := btcspv.NewUint256(h.Difficulty)
 			suite.Equal(expected, actual)
 		}
 	}
@@ -615,7 +696,14 @@ func (suite *UtilsSuite) TestCalculateDifficulty() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual := btcspv.CalculateDifficulty(testCase.Input)
+		actual := btcspv.CalculateDifficulty(testCase.Input, &btcspv.MainNetParams)
 		suite.Equal(expected, actual)
 	}
 }
+
+func (suite *UtilsSuite) TestCalculateWork() {
+	// Difficulty 1 (mainnet genesis) and the regtest pow limit
+	suite.Equal(btcspv.NewUint256(0x100010001), btcspv.CalculateWork(btcspv.NewUint256(0xffff).Lsh(208)))
+	suite.Equal(btcspv.NewUint256(2), btcspv.CalculateWork(btcspv.NewUint256(0x7fffff).Lsh(232)))
+	suite.Equal(btcspv.Uint256{}, btcspv.CalculateWork(btcspv.Uint256{}))
+}
@@ -0,0 +1,244 @@
+package btcspv
+
+// BlockTx is a transaction from a block, split into the fields of an SPVProof
+// Version, Vin, Vout and Locktime exclude the segwit marker, flag and
+// witnesses, so CalculateTxID over them yields TxID. Witnesses holds the
+// witness stack of each input, and is empty for legacy transactions.
+type BlockTx struct {
+	Version   HexBytes      `json:"version"`
+	Vin       HexBytes      `json:"vin"`
+	Vout      HexBytes      `json:"vout"`
+	Locktime  HexBytes      `json:"locktime"`
+	Witnesses [][]HexBytes  `json:"witnesses"`
+	TxID      Hash256Digest `json:"tx_id"`
+}
+
+// Block is a parsed Bitcoin block
+type Block struct {
+	Header       BitcoinHeader `json:"header"`
+	Transactions []BlockTx     `json:"transactions"`
+}
+
+// readVarInt reads a canonical VarInt at offset, returning its value and the
+// offset after it
+func readVarInt(b []byte, offset uint64) (uint64, uint64, error) {
+	if offset >= uint64(len(b)) {
+		return 0, 0, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
+	dataLength, number, err := ParseVarInt(b[offset:])
+	if err != nil {
+		return 0, 0, err
+	}
+	// Like Core, reject VarInts that could have been encoded in fewer bytes
+	if uint64(len(encodeVarInt(number))) != 1+dataLength {
+		return 0, 0, NewSPVError(ErrCodeBadVarInt, "Non-canonical VarInt")
+	}
+	return number, offset + 1 + dataLength, nil
+}
+
+// readVector reads a VarInt-prefixed vector of inputs or outputs at offset
+// Returns the offset after it.
+func readVector(b []byte, offset uint64, determineLength func([]byte) (uint64, error)) (uint64, uint64, error) {
+	count, end, err := readVarInt(b, offset)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := uint64(0); i < count; i++ {
+		if end >= uint64(len(b)) {
+			return 0, 0, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+		}
+		length, err := determineLength(b[end:])
+		if err != nil {
+			return 0, 0, err
+		}
+		if length > uint64(len(b))-end {
+			return 0, 0, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+		}
+		end += length
+	}
+
+	return count, end, nil
+}
+
+// readWitness reads the witness stack of one input at offset
+// Returns the stack items and the offset after them.
+func readWitness(b []byte, offset uint64) ([]HexBytes, uint64, error) {
+	count, offset, err := readVarInt(b, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Every item takes at least one byte
+	if count > uint64(len(b))-offset {
+		return nil, 0, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
+
+	stack := make([]HexBytes, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var length uint64
+		length, offset, err = readVarInt(b, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		if length > uint64(len(b))-offset {
+			return nil, 0, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+		}
+		end := offset + length
+		stack = append(stack, HexBytes(b[offset:end:end]))
+		offset = end
+	}
+
+	return stack, offset, nil
+}
+
+// splitTransaction splits the transaction at the start of b into its fields
+// Returns the transaction and its serialized length, including any witness.
+func splitTransaction(b []byte) (BlockTx, uint64, error) {
+	version, err := safeSlice(b, 0, 4)
+	if err != nil {
+		return BlockTx{}, 0, err
+	}
+
+	// A 0 input count followed by a 1 is the BIP144 marker and flag
+	vinStart := uint64(4)
+	segwit := len(b) > 5 && b[4] == 0 && b[5] == 1
+	if segwit {
+		vinStart = 6
+	}
+
+	nIns, vinEnd, err := readVector(b, vinStart, DetermineInputLength)
+	if err != nil {
+		return BlockTx{}, 0, err
+	}
+	if nIns == 0 {
+		return BlockTx{}, 0, NewSPVError(ErrCodeMalformedTransaction, "Transaction has no inputs")
+	}
+	_, voutEnd, err := readVector(b, vinEnd, DetermineOutputLength)
+	if err != nil {
+		return BlockTx{}, 0, err
+	}
+
+	witnesses := [][]HexBytes{}
+	locktimeStart := voutEnd
+	if segwit {
+		hasWitness := false
+		for i := uint64(0); i < nIns; i++ {
+			var stack []HexBytes
+			stack, locktimeStart, err = readWitness(b, locktimeStart)
+			if err != nil {
+				return BlockTx{}, 0, err
+			}
+			hasWitness = hasWitness || len(stack) != 0
+			witnesses = append(witnesses, stack)
+		}
+		// Core rejects a segwit serialization without witness data
+		if !hasWitness {
+			return BlockTx{}, 0, NewSPVError(ErrCodeMalformedTransaction, "Segwit flag set, but no witness data")
+		}
+	}
+
+	locktime, err := safeSlice(b, locktimeStart, locktimeStart+4)
+	if err != nil {
+		return BlockTx{}, 0, err
+	}
+
+	tx := BlockTx{
+		Version:   version,
+		Vin:       b[vinStart:vinEnd:vinEnd],
+		Vout:      b[vinEnd:voutEnd:voutEnd],
+		Locktime:  locktime,
+		Witnesses: witnesses,
+	}
+	tx.TxID = CalculateTxID(tx.Version, tx.Vin, tx.Vout, tx.Locktime)
+
+	return tx, locktimeStart + 4, nil
+}
+
+// ParseBlock parses a raw serialized block, as returned by `getblock <hash> 0`
+// Errors if the transactions do not hash to the merkle root in the header, or
+// if there are bytes after the last transaction.
+func ParseBlock(raw []byte, height uint32) (Block, error) {
+	rawHeader, err := NewRawHeader(raw)
+	if err != nil {
+		return Block{}, err
+	}
+	header := HeaderFromRaw(rawHeader, height)
+
+	nTxs, offset, err := readVarInt(raw, 80)
+	if err != nil {
+		return Block{}, err
+	}
+	// A transaction is at least 60 bytes
+	if nTxs == 0 || nTxs > uint64(len(raw))/60 {
+		return Block{}, newSPVErrorf(ErrCodeBadLength, "Block with %d bytes cannot hold %d transactions", len(raw), nTxs)
+	}
+
+	txs := make([]BlockTx, 0, nTxs)
+	txids := make([]Hash256Digest, 0, nTxs)
+	for i := uint64(0); i < nTxs; i++ {
+		tx, length, err := splitTransaction(raw[offset:])
+		if err != nil {
+			return Block{}, err
+		}
+		txs = append(txs, tx)
+		txids = append(txids, tx.TxID)
+		offset += length
+	}
+
+	if offset != uint64(len(raw)) {
+		return Block{}, newSPVErrorf(ErrCodeBadLength, "Block has %d bytes after its last transaction", uint64(len(raw))-offset)
+	}
+
+	root, _ := ComputeMerkleRoot(txids)
+	if root != header.MerkleRoot {
+		return Block{}, NewSPVError(ErrCodeWrongMerkleRoot, "Transactions do not match the merkle root of the header")
+	}
+
+	return Block{header, txs}, nil
+}
+
+// TxIDs returns the LE txids of the block's transactions, in block order
+func (b Block) TxIDs() []Hash256Digest {
+	txids := make([]Hash256Digest, len(b.Transactions))
+	for i := range b.Transactions {
+		txids[i] = b.Transactions[i].TxID
+	}
+	return txids
+}
+
+// ProveTxAtIndex builds an SPVProof for the transaction at index in the block
+// The proof is validated before it is returned.
+func (b Block) ProveTxAtIndex(index uint) (SPVProof, error) {
+	intermediateNodes, _, err := BuildMerkleProof(b.TxIDs(), index)
+	if err != nil {
+		return SPVProof{}, err
+	}
+
+	tx := b.Transactions[index]
+	proof := SPVProof{
+		Version:           tx.Version,
+		Vin:               tx.Vin,
+		Vout:              tx.Vout,
+		Locktime:          tx.Locktime,
+		TxID:              tx.TxID,
+		Index:             uint32(index),
+		ConfirmingHeader:  b.Header,
+		IntermediateNodes: intermediateNodes,
+	}
+
+	if _, err := proof.Validate(); err != nil {
+		return SPVProof{}, err
+	}
+	return proof, nil
+}
+
+// ProveTx builds an SPVProof for the transaction with a LE txid in the block
+// The proof is validated before it is returned.
+func (b Block) ProveTx(txid Hash256Digest) (SPVProof, error) {
+	for i := range b.Transactions {
+		if b.Transactions[i].TxID == txid {
+			return b.ProveTxAtIndex(uint(i))
+		}
+	}
+	return SPVProof{}, NewSPVError(ErrCodeTxIDMismatch, "Transaction is not in the block")
+}
@@ -0,0 +1,117 @@
+package btcspv_test
+
+import (
+	"encoding/hex"
+	"errors"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// testBlock builds a block holding a coinbase, the valid test proof's
+// transaction and a segwit serialization of it with one witness stack
+func (suite *TypesSuite) testBlock(witness []byte) ([]byte, []byte) {
+	proof := suite.ValidProofs[0]
+
+	coinbase, _ := hex.DecodeString(
+		"01000000" +
+			"01" + "0000000000000000000000000000000000000000000000000000000000000000" + "ffffffff" +
+			"03" + "012345" + "ffffffff" +
+			"01" + "00f2052a01000000" + "01" + "51" +
+			"00000000")
+
+	legacy := []byte{}
+	legacy = append(legacy, proof.Version...)
+	legacy = append(legacy, proof.Vin...)
+	legacy = append(legacy, proof.Vout...)
+	legacy = append(legacy, proof.Locktime...)
+
+	// Change the version, so the segwit tx has a different txid
+	segwit := []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	segwit = append(segwit, proof.Vin...)
+	segwit = append(segwit, proof.Vout...)
+	segwit = append(segwit, witness...)
+	segwit = append(segwit, proof.Locktime...)
+
+	txids := []Hash256Digest{
+		btcspv.Hash256(coinbase),
+		btcspv.Hash256(legacy),
+		btcspv.CalculateTxID([]byte{0x02, 0x00, 0x00, 0x00}, proof.Vin, proof.Vout, proof.Locktime),
+	}
+	root, _ := btcspv.ComputeMerkleRoot(txids)
+
+	header := proof.ConfirmingHeader.Raw
+	copy(header[36:68], root[:])
+
+	block := []byte{}
+	block = append(block, header[:]...)
+	block = append(block, 0x03)
+	block = append(block, coinbase...)
+	block = append(block, legacy...)
+	block = append(block, segwit...)
+	return block, segwit
+}
+
+func (suite *TypesSuite) TestParseBlock() {
+	proof := suite.ValidProofs[0]
+	witness, _ := hex.DecodeString("02" + "03" + "aabbcc" + "01" + "dd")
+	raw, segwit := suite.testBlock(witness)
+
+	block, err := btcspv.ParseBlock(raw, 1000)
+	suite.Nil(err)
+	suite.Equal(uint32(1000), block.Header.Height)
+	suite.Len(block.Transactions, 3)
+	suite.Equal(proof.TxID, block.Transactions[1].TxID)
+	suite.Equal(HexBytes(proof.Vin), block.Transactions[1].Vin)
+	suite.Len(block.Transactions[1].Witnesses, 0)
+
+	tx := block.Transactions[2]
+	suite.Equal(HexBytes(proof.Vin), tx.Vin)
+	suite.Equal(HexBytes(proof.Vout), tx.Vout)
+	suite.Equal(HexBytes(proof.Locktime), tx.Locktime)
+	suite.Equal([][]HexBytes{{{0xaa, 0xbb, 0xcc}, {0xdd}}}, tx.Witnesses)
+	suite.NotEqual(btcspv.Hash256(segwit), tx.TxID)
+
+	for i := range block.Transactions {
+		spvProof, err := block.ProveTxAtIndex(uint(i))
+		suite.Nil(err)
+		suite.Equal(uint32(i), spvProof.Index)
+		suite.Equal(block.Transactions[i].TxID, spvProof.TxID)
+	}
+
+	spvProof, err := block.ProveTx(proof.TxID)
+	suite.Nil(err)
+	suite.Equal(uint32(1), spvProof.Index)
+	valid, err := spvProof.Validate()
+	suite.Nil(err)
+	suite.True(valid)
+
+	_, err = block.ProveTx(Hash256Digest{})
+	suite.True(errors.Is(err, btcspv.ErrTxIDMismatch))
+	_, err = block.ProveTxAtIndex(3)
+	suite.True(errors.Is(err, btcspv.ErrOutOfRange))
+}
+
+func (suite *TypesSuite) TestParseBlockError() {
+	witness, _ := hex.DecodeString("02" + "03" + "aabbcc" + "01" + "dd")
+	raw, _ := suite.testBlock(witness)
+
+	// The header must commit to the transactions
+	wrongRoot := append([]byte{}, raw...)
+	wrongRoot[40] ^= 1
+	_, err := btcspv.ParseBlock(wrongRoot, 0)
+	suite.True(errors.Is(err, btcspv.ErrWrongMerkleRoot))
+
+	_, err = btcspv.ParseBlock(append(raw, 0x00), 0)
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+
+	// Every truncation fails cleanly
+	for i := 0; i < len(raw); i++ {
+		_, err = btcspv.ParseBlock(raw[:i], 0)
+		suite.NotNil(err)
+	}
+
+	// A segwit serialization must have witness data
+	raw, _ = suite.testBlock([]byte{0x00})
+	_, err = btcspv.ParseBlock(raw, 0)
+	suite.True(errors.Is(err, btcspv.ErrMalformedTransaction))
+}
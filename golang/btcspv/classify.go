@@ -0,0 +1,180 @@
+package btcspv
+
+import "github.com/summa-tx/bitcoin-spv/golang/btcspv/script"
+
+// OutputType is the type of a transaction output
+// Values and names match the OUTPUT_TYPES table in testVectors.json.
+type OutputType int
+
+// possible output types
+const (
+	OutputNone           OutputType = 0
+	OutputWPKH           OutputType = 1
+	OutputWSH            OutputType = 2
+	OutputOpReturn       OutputType = 3
+	OutputPKH            OutputType = 4
+	OutputSH             OutputType = 5
+	OutputNonstandard    OutputType = 6
+	OutputTR             OutputType = 7
+	OutputPK             OutputType = 8
+	OutputMultisig       OutputType = 9
+	OutputWitnessUnknown OutputType = 10
+)
+
+// String returns the name of the output type
+func (t OutputType) String() string {
+	switch t {
+	case OutputNone:
+		return "NONE"
+	case OutputWPKH:
+		return "WPKH"
+	case OutputWSH:
+		return "WSH"
+	case OutputOpReturn:
+		return "OP_RETURN"
+	case OutputPKH:
+		return "PKH"
+	case OutputSH:
+		return "SH"
+	case OutputNonstandard:
+		return "NONSTANDARD"
+	case OutputTR:
+		return "TR"
+	case OutputPK:
+		return "PK"
+	case OutputMultisig:
+		return "MULTISIG"
+	case OutputWitnessUnknown:
+		return "WITNESS_UNKNOWN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// InputType is the type of a transaction input
+// Values and names match the INPUT_TYPES table in testVectors.json.
+type InputType int
+
+// possible input types
+const (
+	InputNone          InputType = 0
+	InputLegacy        InputType = 1
+	InputCompatibility InputType = 2
+	InputWitness       InputType = 3
+)
+
+// String returns the name of the input type
+func (t InputType) String() string {
+	switch t {
+	case InputNone:
+		return "NONE"
+	case InputLegacy:
+		return "LEGACY"
+	case InputCompatibility:
+		return "COMPATIBILITY"
+	case InputWitness:
+		return "WITNESS"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// outputTypeOfClass maps a script class to an output type
+func outputTypeOfClass(class script.Class) OutputType {
+	switch class {
+	case script.PubKey:
+		return OutputPK
+	case script.PubKeyHash:
+		return OutputPKH
+	case script.ScriptHash:
+		return OutputSH
+	case script.MultiSig:
+		return OutputMultisig
+	case script.NullData:
+		return OutputOpReturn
+	case script.WitnessV0KeyHash:
+		return OutputWPKH
+	case script.WitnessV0ScriptHash:
+		return OutputWSH
+	case script.WitnessV1Taproot:
+		return OutputTR
+	case script.WitnessUnknown:
+		return OutputWitnessUnknown
+	default:
+		return OutputNonstandard
+	}
+}
+
+// ClassifyOutput returns the type of an output, and the payload it commits to
+// The payload is the pubkey hash, script hash, witness program or pubkey of
+// the output, or the first element pushed by an OP_RETURN. Multisig and
+// nonstandard outputs have an empty payload. OP_RETURN outputs must only push
+// data, as under Core's standardness rules.
+func ClassifyOutput(output []byte) (OutputType, []byte, error) {
+	length, err := DetermineOutputLength(output)
+	if err != nil {
+		return OutputNone, nil, err
+	}
+	if length != uint64(len(output)) {
+		return OutputNone, nil, NewSPVError(ErrCodeBadLength, "Reported length mismatch")
+	}
+
+	dataLength, _, _ := ParseVarInt(output[8:])
+	pkScript := output[9+dataLength:]
+	class, solutions := script.Classify(pkScript)
+	outputType := outputTypeOfClass(class)
+
+	switch outputType {
+	case OutputOpReturn:
+		pushes, err := ExtractOpReturnPushes(output)
+		if err != nil {
+			return OutputNone, nil, err
+		}
+		if len(pushes) == 0 {
+			return outputType, []byte{}, nil
+		}
+		return outputType, pushes[0], nil
+	case OutputWitnessUnknown:
+		return outputType, solutions[1], nil
+	case OutputMultisig, OutputNonstandard:
+		return outputType, []byte{}, nil
+	default:
+		return outputType, solutions[0], nil
+	}
+}
+
+// ClassifyInput returns the type of an input
+// An input is compatibility if its scriptSig is a single push of a witness
+// program, as when spending P2SH-P2WPKH or P2SH-P2WSH. For compatibility
+// inputs, the returned OutputType is the type of the nested witness program,
+// and is OutputNone otherwise. Taproot rules do not apply to nested programs,
+// so a nested version 1 program is OutputWitnessUnknown.
+func ClassifyInput(input []byte) (InputType, OutputType, error) {
+	isLegacy, err := IsLegacyInput(input)
+	if err != nil {
+		return InputNone, OutputNone, err
+	}
+	if !isLegacy {
+		return InputWitness, OutputNone, nil
+	}
+
+	scriptSig, err := ExtractScriptSig(input)
+	if err != nil {
+		return InputNone, OutputNone, err
+	}
+	dataLength, _, _ := ParseVarInt(scriptSig)
+
+	ops, err := script.Parse(scriptSig[1+dataLength:])
+	if err != nil || len(ops) != 1 || ops[0].Opcode > script.OP_PUSHDATA4 || !ops[0].IsMinimalPush() {
+		return InputLegacy, OutputNone, nil
+	}
+	if _, _, ok := script.WitnessProgram(ops[0].Data); !ok {
+		return InputLegacy, OutputNone, nil
+	}
+
+	class, _ := script.Classify(ops[0].Data)
+	if class == script.WitnessV1Taproot {
+		return InputCompatibility, OutputWitnessUnknown, nil
+	}
+	return InputCompatibility, outputTypeOfClass(class), nil
+}
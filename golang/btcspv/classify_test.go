@@ -0,0 +1,94 @@
+package btcspv_test
+
+import (
+	"strings"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+func (suite *UtilsSuite) TestTypeNames() {
+	for name, value := range suite.Fixtures.InputTypes {
+		suite.Equal(name, btcspv.InputType(value).String())
+	}
+	for name, value := range suite.Fixtures.OutputTypes[0] {
+		suite.Equal(name, btcspv.OutputType(value).String())
+	}
+}
+
+func (suite *UtilsSuite) TestClassifyOutput() {
+	hash20 := "751e76e8199196d454941c45d1b3a323f1433bd6"
+	hash32 := "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"
+	pubkey := "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+	cases := []struct {
+		script     string
+		outputType btcspv.OutputType
+		payload    string
+	}{
+		{"160014" + hash20, btcspv.OutputWPKH, hash20},
+		{"220020" + hash32, btcspv.OutputWSH, hash32},
+		{"0c6a0a68656c6c6f776f726c64", btcspv.OutputOpReturn, "68656c6c6f776f726c64"},
+		{"016a", btcspv.OutputOpReturn, ""},
+		{"026a50", btcspv.OutputOpReturn, ""},
+		{"1976a914" + hash20 + "88ac", btcspv.OutputPKH, hash20},
+		{"17a914" + hash20 + "87", btcspv.OutputSH, hash20},
+		{"025100", btcspv.OutputNonstandard, ""},
+		{"026a76", btcspv.OutputNonstandard, ""},
+		{"036a0201", btcspv.OutputNonstandard, ""},
+		{"225120" + hash32, btcspv.OutputTR, hash32},
+		{"2321" + pubkey + "ac", btcspv.OutputPK, pubkey},
+		{"255121" + pubkey + "51ae", btcspv.OutputMultisig, ""},
+		{"165214" + hash20, btcspv.OutputWitnessUnknown, hash20},
+	}
+
+	for _, c := range cases {
+		outputType, payload, err := btcspv.ClassifyOutput(decodeHex("0000000000000000" + c.script))
+		suite.Nil(err, c.script)
+		suite.Equal(c.outputType, outputType, c.script)
+		suite.Equal(decodeHex(c.payload), payload, c.script)
+	}
+
+	_, _, err := btcspv.ClassifyOutput(decodeHex("0000000000000000" + "17a914" + hash20))
+	suite.EqualError(err, "Reported length mismatch")
+	_, _, err = btcspv.ClassifyOutput(decodeHex("00000000"))
+	suite.EqualError(err, "Read overrun")
+}
+
+func (suite *UtilsSuite) TestClassifyInput() {
+	outpoint := strings.Repeat("11", 32) + "00000000"
+	hash20 := strings.Repeat("22", 20)
+	hash32 := strings.Repeat("33", 32)
+	sig := "47" + strings.Repeat("44", 71)
+
+	cases := []struct {
+		scriptSig  string
+		inputType  btcspv.InputType
+		nestedType btcspv.OutputType
+	}{
+		{"00", btcspv.InputWitness, btcspv.OutputNone},
+		{"17160014" + hash20, btcspv.InputCompatibility, btcspv.OutputWPKH},
+		{"23220020" + hash32, btcspv.InputCompatibility, btcspv.OutputWSH},
+		{"23225120" + hash32, btcspv.InputCompatibility, btcspv.OutputWitnessUnknown},
+		{"48" + sig, btcspv.InputLegacy, btcspv.OutputNone},
+		{"6a" + sig + "21" + "02" + hash32, btcspv.InputLegacy, btcspv.OutputNone},
+
+		// Not a single minimal push of a witness program
+		{"160014" + hash20, btcspv.InputLegacy, btcspv.OutputNone},
+		{"184c160014" + hash20, btcspv.InputLegacy, btcspv.OutputNone},
+		{"1817160014" + hash20, btcspv.InputLegacy, btcspv.OutputNone},
+		{"18170014" + hash20 + "00", btcspv.InputLegacy, btcspv.OutputNone},
+		{"014c", btcspv.InputLegacy, btcspv.OutputNone},
+	}
+
+	for _, c := range cases {
+		inputType, nestedType, err := btcspv.ClassifyInput(decodeHex(outpoint + c.scriptSig + "ffffffff"))
+		suite.Nil(err, c.scriptSig)
+		suite.Equal(c.inputType, inputType, c.scriptSig)
+		suite.Equal(c.nestedType, nestedType, c.scriptSig)
+	}
+
+	_, _, err := btcspv.ClassifyInput(decodeHex(outpoint))
+	suite.EqualError(err, "Read overrun")
+	_, _, err = btcspv.ClassifyInput(decodeHex(outpoint + "17160014"))
+	suite.EqualError(err, "Read overrun")
+}
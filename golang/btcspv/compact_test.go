@@ -0,0 +1,82 @@
+package btcspv_test
+
+import (
+	"encoding/hex"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// Vectors from Bitcoin Core's arith_uint256_tests.cpp bignum_SetCompact
+var compactVectors = []struct {
+	bits     uint32
+	target   string
+	compact  uint32
+	negative bool
+	overflow bool
+}{
+	{0x00000000, "00", 0x00000000, false, false},
+	{0x00123456, "00", 0x00000000, false, false},
+	{0x01003456, "00", 0x00000000, false, false},
+	{0x02000056, "00", 0x00000000, false, false},
+	{0x03000000, "00", 0x00000000, false, false},
+	{0x04000000, "00", 0x00000000, false, false},
+	{0x00923456, "00", 0x00000000, false, false},
+	{0x01803456, "00", 0x00000000, false, false},
+	{0x02800056, "00", 0x00000000, false, false},
+	{0x03800000, "00", 0x00000000, false, false},
+	{0x04800000, "00", 0x00000000, false, false},
+	{0x01123456, "12", 0x01120000, false, false},
+	{0x01fedcba, "7e", 0x01fe0000, true, false},
+	{0x02123456, "1234", 0x02123400, false, false},
+	{0x03123456, "123456", 0x03123456, false, false},
+	{0x04123456, "12345600", 0x04123456, false, false},
+	{0x04923456, "12345600", 0x04923456, true, false},
+	{0x05009234, "92340000", 0x05009234, false, false},
+	{0x20123456, "1234560000000000000000000000000000000000000000000000000000000000", 0x20123456, false, false},
+	{0xff123456, "00", 0x00000000, false, true},
+}
+
+func (suite *UtilsSuite) TestCompactToTarget() {
+	for _, tc := range compactVectors {
+		expected, _ := hex.DecodeString(tc.target)
+
+		target, negative, overflow := btcspv.CompactToTarget(tc.bits)
+		suite.Equal(tc.negative, negative, "%08x", tc.bits)
+		suite.Equal(tc.overflow, overflow, "%08x", tc.bits)
+		if !overflow {
+			suite.Equal(btcspv.BytesToBigUint(expected), target, "%08x", tc.bits)
+			suite.Equal(tc.compact, btcspv.TargetToCompact(target, negative), "%08x", tc.bits)
+		}
+	}
+}
+
+func (suite *UtilsSuite) TestTargetToCompact() {
+	// Never generate compacts with the 0x00800000 bit set
+	suite.Equal(uint32(0x02008000), btcspv.TargetToCompact(btcspv.NewUint256(0x80), false))
+
+	// Negative zero is not negative
+	suite.Equal(uint32(0), btcspv.TargetToCompact(Uint256{}, true))
+
+	// Truncation to a 23 bit mantissa
+	target := btcspv.NewUint256(0x123456789a)
+	suite.Equal(uint32(0x05123456), btcspv.TargetToCompact(target, false))
+
+	fixture := suite.Fixtures.RetargetAlgorithm
+	for i := range fixture {
+		for _, h := range fixture[i].Input {
+			bits := btcspv.ExtractBits(h.Hex)
+			suite.Equal(bits, btcspv.TargetToCompact(btcspv.ExtractTarget(h.Hex), false))
+		}
+	}
+}
+
+func (suite *UtilsSuite) TestExtractTargetNegative() {
+	var header RawHeader
+	header[72] = 0x56
+	header[73] = 0x34
+	header[74] = 0x92
+	header[75] = 0x04
+
+	suite.Equal(uint32(0x04923456), btcspv.ExtractBits(header))
+	suite.Equal(Uint256{}, btcspv.ExtractTarget(header))
+}
@@ -0,0 +1,186 @@
+package btcspv
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// SPVErrorCode is a stable numeric identifier for a class of SPVError
+// The first three codes line up with the ViewSPV error sentinels in the
+// Solidity implementation. See ViewSPVCode.
+type SPVErrorCode uint
+
+// possible error codes
+const (
+	ErrCodeUnknown                    SPVErrorCode = 0
+	ErrCodeBadLength                  SPVErrorCode = 1
+	ErrCodeInvalidChain               SPVErrorCode = 2
+	ErrCodeLowWork                    SPVErrorCode = 3
+	ErrCodeReadOverrun                SPVErrorCode = 4
+	ErrCodeBadVarInt                  SPVErrorCode = 5
+	ErrCodeMalformedInput             SPVErrorCode = 6
+	ErrCodeMalformedOutput            SPVErrorCode = 7
+	ErrCodeInvalidVin                 SPVErrorCode = 8
+	ErrCodeInvalidVout                SPVErrorCode = 9
+	ErrCodeTxIDMismatch               SPVErrorCode = 10
+	ErrCodeBadMerkleProof             SPVErrorCode = 11
+	ErrCodeWrongDigest                SPVErrorCode = 12
+	ErrCodeWrongMerkleRoot            SPVErrorCode = 13
+	ErrCodeWrongPrevHash              SPVErrorCode = 14
+	ErrCodeOutOfRange                 SPVErrorCode = 15
+	ErrCodeUnexpectedDifficultyChange SPVErrorCode = 16
+	ErrCodeMalformedTransaction       SPVErrorCode = 17
+	ErrCodeInvalidSignature           SPVErrorCode = 18
+	ErrCodeInvalidPubkey              SPVErrorCode = 19
+	ErrCodeWrongPayee                 SPVErrorCode = 20
+	ErrCodeInsufficientValue          SPVErrorCode = 21
+	ErrCodeInsufficientWork           SPVErrorCode = 22
+	ErrCodeInsufficientConfirmations  SPVErrorCode = 23
+)
+
+// String returns the name of the error code
+func (c SPVErrorCode) String() string {
+	switch c {
+	case ErrCodeBadLength:
+		return "BadLength"
+	case ErrCodeInvalidChain:
+		return "InvalidChain"
+	case ErrCodeLowWork:
+		return "LowWork"
+	case ErrCodeReadOverrun:
+		return "ReadOverrun"
+	case ErrCodeBadVarInt:
+		return "BadVarInt"
+	case ErrCodeMalformedInput:
+		return "MalformedInput"
+	case ErrCodeMalformedOutput:
+		return "MalformedOutput"
+	case ErrCodeInvalidVin:
+		return "InvalidVin"
+	case ErrCodeInvalidVout:
+		return "InvalidVout"
+	case ErrCodeTxIDMismatch:
+		return "TxIDMismatch"
+	case ErrCodeBadMerkleProof:
+		return "BadMerkleProof"
+	case ErrCodeWrongDigest:
+		return "WrongDigest"
+	case ErrCodeWrongMerkleRoot:
+		return "WrongMerkleRoot"
+	case ErrCodeWrongPrevHash:
+		return "WrongPrevHash"
+	case ErrCodeOutOfRange:
+		return "OutOfRange"
+	case ErrCodeUnexpectedDifficultyChange:
+		return "UnexpectedDifficultyChange"
+	case ErrCodeMalformedTransaction:
+		return "MalformedTransaction"
+	case ErrCodeInvalidSignature:
+		return "InvalidSignature"
+	case ErrCodeInvalidPubkey:
+		return "InvalidPubkey"
+	case ErrCodeWrongPayee:
+		return "WrongPayee"
+	case ErrCodeInsufficientValue:
+		return "InsufficientValue"
+	case ErrCodeInsufficientWork:
+		return "InsufficientWork"
+	case ErrCodeInsufficientConfirmations:
+		return "InsufficientConfirmations"
+	default:
+		return "Unknown"
+	}
+}
+
+// ViewSPVCode returns the uint256 error sentinel used by the Solidity ViewSPV
+// library for this code. ErrCodeBadLength maps to ViewSPV.getErrBadLength,
+// ErrCodeInvalidChain to getErrInvalidChain and ErrCodeLowWork to getErrLowWork.
+// The sentinels count down from 2^256 - 1, so every code maps to 2^256 - code.
+// ErrCodeUnknown has no sentinel, as 2^256 does not fit in a uint256, and
+// maps to nil.
+func (c SPVErrorCode) ViewSPVCode() *big.Int {
+	if c == ErrCodeUnknown {
+		return nil
+	}
+	max := new(big.Int).Lsh(big.NewInt(1), 256)
+	return max.Sub(max, new(big.Int).SetUint64(uint64(c)))
+}
+
+// SPVError is the error type returned by btcspv parsing and validation
+// Callers should branch on Code rather than on the message
+type SPVError struct {
+	Code    SPVErrorCode
+	Message string
+}
+
+// Error implements the error interface
+func (e *SPVError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is an SPVError with the same Code.
+// This allows errors.Is(err, btcspv.ErrInvalidChain).
+func (e *SPVError) Is(target error) bool {
+	t, ok := target.(*SPVError)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code
+}
+
+// NewSPVError instantiates an SPVError with a code and message
+func NewSPVError(code SPVErrorCode, message string) *SPVError {
+	return &SPVError{code, message}
+}
+
+// newSPVErrorf instantiates an SPVError with a formatted message
+func newSPVErrorf(code SPVErrorCode, format string, a ...interface{}) *SPVError {
+	return NewSPVError(code, fmt.Sprintf(format, a...))
+}
+
+// Sentinel errors for use with errors.Is. Only the Code is compared.
+var (
+	ErrBadLength       = NewSPVError(ErrCodeBadLength, "Bad length")
+	ErrInvalidChain    = NewSPVError(ErrCodeInvalidChain, "Invalid chain")
+	ErrLowWork         = NewSPVError(ErrCodeLowWork, "Low work")
+	ErrReadOverrun     = NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	ErrBadVarInt       = NewSPVError(ErrCodeBadVarInt, "Bad VarInt")
+	ErrMalformedInput  = NewSPVError(ErrCodeMalformedInput, "Malformed input")
+	ErrMalformedOutput = NewSPVError(ErrCodeMalformedOutput, "Malformed output")
+	ErrInvalidVin      = NewSPVError(ErrCodeInvalidVin, "Vin is not valid")
+	ErrInvalidVout     = NewSPVError(ErrCodeInvalidVout, "Vout is not valid")
+	ErrTxIDMismatch    = NewSPVError(ErrCodeTxIDMismatch, "TxID mismatch")
+	ErrBadMerkleProof  = NewSPVError(ErrCodeBadMerkleProof, "Merkle Proof is not valid")
+	ErrWrongDigest     = NewSPVError(ErrCodeWrongDigest, "Wrong digest")
+	ErrWrongMerkleRoot = NewSPVError(ErrCodeWrongMerkleRoot, "Wrong merkle root")
+	ErrWrongPrevHash   = NewSPVError(ErrCodeWrongPrevHash, "Wrong prevhash")
+	ErrOutOfRange      = NewSPVError(ErrCodeOutOfRange, "Out of range")
+
+	ErrUnexpectedDifficultyChange = NewSPVError(ErrCodeUnexpectedDifficultyChange, "Unexpected difficulty change")
+	ErrMalformedTransaction       = NewSPVError(ErrCodeMalformedTransaction, "Malformed transaction")
+	ErrInvalidSignature           = NewSPVError(ErrCodeInvalidSignature, "Signature is not valid")
+	ErrInvalidPubkey              = NewSPVError(ErrCodeInvalidPubkey, "Pubkey is not valid")
+	ErrWrongPayee                 = NewSPVError(ErrCodeWrongPayee, "Wrong payee")
+	ErrInsufficientValue          = NewSPVError(ErrCodeInsufficientValue, "Insufficient value")
+	ErrInsufficientWork           = NewSPVError(ErrCodeInsufficientWork, "Insufficient work")
+	ErrInsufficientConfirmations  = NewSPVError(ErrCodeInsufficientConfirmations, "Insufficient confirmations")
+)
+
+// HeaderError identifies the header that failed header chain validation
+// It wraps an SPVError, so errors.Is and errors.As see through it.
+type HeaderError struct {
+	Index  int
+	Height uint32
+	Digest Hash256Digest
+	Err    *SPVError
+}
+
+// Error implements the error interface
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("%s at index %d (height %d)", e.Err.Message, e.Index, e.Height)
+}
+
+// Unwrap returns the underlying SPVError
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,81 @@
+package btcspv_test
+
+import (
+	"encoding/hex"
+	"errors"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+func (suite *UtilsSuite) TestViewSPVCode() {
+	codes := map[btcspv.SPVErrorCode]string{
+		btcspv.ErrCodeBadLength:    "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		btcspv.ErrCodeInvalidChain: "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe",
+		btcspv.ErrCodeLowWork:      "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffd",
+	}
+
+	for code, expected := range codes {
+		actual := hex.EncodeToString(code.ViewSPVCode().Bytes())
+		suite.Equal(expected, actual)
+	}
+
+	suite.Nil(btcspv.ErrCodeUnknown.ViewSPVCode())
+	suite.Equal(32, len(btcspv.ErrCodeInsufficientConfirmations.ViewSPVCode().Bytes()))
+}
+
+func (suite *UtilsSuite) TestSPVErrorIs() {
+	_, err := btcspv.ValidateHeaderChain(make([]byte, 79), &btcspv.MainNetParams)
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+	suite.False(errors.Is(err, btcspv.ErrInvalidChain))
+
+	var spvErr *btcspv.SPVError
+	suite.True(errors.As(err, &spvErr))
+	suite.Equal(btcspv.ErrCodeBadLength, spvErr.Code)
+	suite.Equal("Header bytes not multiple of 80", spvErr.Error())
+
+	fixture := suite.Fixtures.ValidateHeaderChainError
+	for i := range fixture {
+		_, err := btcspv.ValidateHeaderChain(fixture[i].Input, &btcspv.MainNetParams)
+		suite.True(errors.As(err, &spvErr))
+	}
+
+	_, err = btcspv.ExtractInputAtIndex([]byte{0x01}, 3)
+	suite.True(errors.Is(err, btcspv.ErrReadOverrun))
+
+	_, err = btcspv.ExtractOutputAtIndex([]byte{0xfd}, 0)
+	suite.True(errors.Is(err, btcspv.ErrBadVarInt))
+
+	_, err = btcspv.ExtractHash(append(make([]byte, 8), 0x01, 0x51))
+	suite.True(errors.Is(err, btcspv.ErrMalformedOutput))
+}
+
+func (suite *TypesSuite) TestSPVErrorCodes() {
+	invalidProofs := suite.Fixtures.InvalidProofs
+	expected := []btcspv.SPVErrorCode{
+		btcspv.ErrCodeInvalidVin,
+		btcspv.ErrCodeInvalidVout,
+		btcspv.ErrCodeTxIDMismatch,
+		btcspv.ErrCodeBadMerkleProof,
+	}
+
+	for i := range invalidProofs {
+		_, err := invalidProofs[i].Proof.Validate()
+		var spvErr *btcspv.SPVError
+		suite.True(errors.As(err, &spvErr))
+		suite.Equal(expected[i], spvErr.Code)
+	}
+
+	invalidHeaders := suite.Fixtures.InvalidHeaders
+	expected = []btcspv.SPVErrorCode{
+		btcspv.ErrCodeWrongDigest,
+		btcspv.ErrCodeWrongMerkleRoot,
+		btcspv.ErrCodeWrongPrevHash,
+	}
+
+	for i := range invalidHeaders {
+		_, err := invalidHeaders[i].Header.Validate()
+		var spvErr *btcspv.SPVError
+		suite.True(errors.As(err, &spvErr))
+		suite.Equal(expected[i], spvErr.Code)
+	}
+}
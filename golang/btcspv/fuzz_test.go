@@ -0,0 +1,166 @@
+package btcspv_test
+
+import (
+	"math/rand"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// fuzzInputs returns every prefix of each seed, plus some random bytestrings
+func fuzzInputs(seeds []HexBytes) [][]byte {
+	var inputs [][]byte
+	for i := range seeds {
+		for j := 0; j <= len(seeds[i]); j++ {
+			inputs = append(inputs, seeds[i][:j:j])
+		}
+	}
+
+	// Fixed seed so that failures are reproducible
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 2000; i++ {
+		buf := make([]byte, r.Intn(128))
+		r.Read(buf)
+
+		// Bias towards interesting VarInt flags and script tags
+		if len(buf) > 0 && i%4 == 0 {
+			buf[0] = []byte{0xfd, 0xfe, 0xff, 0x01}[r.Intn(4)]
+		}
+		if len(buf) > 10 && i%3 == 0 {
+			// Declare the script length exactly, too short, leaving bytes
+			// after the script, or too long
+			length := len(buf) - 9
+			switch r.Intn(3) {
+			case 1:
+				length = r.Intn(length + 1)
+			case 2:
+				length += 1 + r.Intn(4)
+			}
+			buf[8] = byte(length)
+			buf[9] = []byte{0x00, 0x6a, 0x76, 0xa9}[r.Intn(4)]
+			if 9+length < len(buf) && r.Intn(2) == 0 {
+				buf[9+length] = 0x6a
+			}
+		}
+		inputs = append(inputs, buf)
+	}
+
+	return inputs
+}
+
+func (suite *UtilsSuite) TestParsersDoNotPanic() {
+	var seeds []HexBytes
+	for _, tc := range suite.Fixtures.ExtractInputAtIndex {
+		seeds = append(seeds, tc.Input.Vin, tc.Output)
+	}
+	for _, tc := range suite.Fixtures.ExtractOutputAtIndex {
+		seeds = append(seeds, tc.Input.Vout, tc.Output)
+	}
+	for _, tc := range suite.Fixtures.ExtractHash {
+		seeds = append(seeds, tc.Input)
+	}
+	for _, tc := range suite.Fixtures.ExtractOpReturnData {
+		seeds = append(seeds, tc.Input)
+	}
+	for _, tc := range suite.Fixtures.ExtractSequenceLegacy {
+		seeds = append(seeds, tc.Input)
+	}
+	for _, tc := range suite.Fixtures.ValidateVin {
+		seeds = append(seeds, tc.Input)
+	}
+	for _, tc := range suite.Fixtures.ValidateVout {
+		seeds = append(seeds, tc.Input)
+	}
+
+	for _, input := range fuzzInputs(seeds) {
+		b := input
+		suite.NotPanics(func() {
+			btcspv.ParseVarInt(b)
+			btcspv.LastBytes(b, 4)
+			btcspv.ExtractInputAtIndex(b, 0)
+			btcspv.ExtractInputAtIndex(b, 1)
+			btcspv.ExtractInputAtIndex(b, 3)
+			btcspv.IsLegacyInput(b)
+			btcspv.ExtractScriptSigLen(b)
+			btcspv.DetermineInputLength(b)
+			btcspv.ExtractSequenceLELegacy(b)
+			btcspv.ExtractSequenceLegacy(b)
+			btcspv.ExtractScriptSig(b)
+			btcspv.ExtractSequenceLEWitness(b)
+			btcspv.ExtractSequenceWitness(b)
+			btcspv.ExtractOutpoint(b)
+			btcspv.ExtractInputTxIDLE(b)
+			btcspv.ExtractTxIndexLE(b)
+			btcspv.ExtractTxIndex(b)
+			btcspv.DetermineOutputLength(b)
+			btcspv.ExtractOutputAtIndex(b, 0)
+			btcspv.ExtractOutputAtIndex(b, 1)
+			btcspv.ExtractOutputAtIndex(b, 3)
+			btcspv.ExtractValueLE(b)
+			btcspv.ExtractValue(b)
+			btcspv.ExtractOpReturnData(b)
+			btcspv.ExtractOpReturnPushes(b)
+			btcspv.ClassifyOutput(b)
+			btcspv.ClassifyInput(b)
+			btcspv.ExtractHash(b)
+			btcspv.ValidateVin(b)
+			btcspv.ValidateVout(b)
+			btcspv.VerifyHash256Merkle(b, 1)
+			btcspv.ParseTransaction(b)
+			btcspv.NewTransaction(btcspv.BlockTx{Vin: b, Vout: b})
+			btcspv.ParseBlock(b, 0)
+		}, "input: %x", b)
+	}
+
+	// Counts too large to allocate must be rejected, not used as capacities
+	huge := HexBytes{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	word := HexBytes{1, 0, 0, 0}
+	suite.NotPanics(func() {
+		_, err := btcspv.NewTransaction(btcspv.BlockTx{Version: word, Vin: huge, Vout: HexBytes{0}, Locktime: word})
+		suite.EqualError(err, "Read overrun")
+		_, err = btcspv.NewTransaction(btcspv.BlockTx{Version: word, Vin: HexBytes{0}, Vout: huge, Locktime: word})
+		suite.EqualError(err, "Read overrun")
+
+		raw := append(append([]byte{}, word...), huge...)
+		_, err = btcspv.ParseTransaction(append(raw, make([]byte, 64)...))
+		suite.NotNil(err)
+	})
+}
+
+func (suite *UtilsSuite) TestParsersRejectTruncatedInputs() {
+	fixture := suite.Fixtures.ExtractInputAtIndex
+	for i := range fixture {
+		input := fixture[i].Output
+		for j := 0; j < 36; j++ {
+			_, err := btcspv.ExtractOutpoint(input[:j])
+			suite.EqualError(err, "Read overrun")
+		}
+		for j := 0; j < 37; j++ {
+			_, err := btcspv.IsLegacyInput(input[:j])
+			suite.EqualError(err, "Read overrun")
+		}
+	}
+
+	outputs := suite.Fixtures.ExtractOutputAtIndex
+	for i := range outputs {
+		output := outputs[i].Output
+		for j := 0; j < 8; j++ {
+			_, err := btcspv.ExtractValue(output[:j])
+			suite.EqualError(err, "Read overrun")
+		}
+		for j := 0; j < 9; j++ {
+			_, err := btcspv.ExtractHash(output[:j])
+			suite.EqualError(err, "Read overrun")
+		}
+	}
+
+	// A scriptSig length that would overflow a uint64 offset
+	input := make([]byte, 45)
+	input[36] = 0xff
+	for i := 37; i < 45; i++ {
+		input[i] = 0xff
+	}
+	_, err := btcspv.DetermineInputLength(input)
+	suite.EqualError(err, "ScriptSig length overflows")
+	_, err = btcspv.ExtractScriptSig(input)
+	suite.EqualError(err, "Read overrun")
+}
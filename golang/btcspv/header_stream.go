@@ -0,0 +1,198 @@
+package btcspv
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// HeaderFraming is the layout of headers in a stream
+type HeaderFraming int
+
+// possible header framings
+const (
+	// FramingRaw is concatenated 80-byte headers, as passed to
+	// ValidateHeaderChain
+	FramingRaw HeaderFraming = 0
+
+	// FramingHeadersMessage is one or more payloads of the P2P headers
+	// message, as serialized by Core: a VarInt count, then that many headers,
+	// each followed by a VarInt transaction count of 0
+	FramingHeadersMessage HeaderFraming = 1
+)
+
+// defaultProgressInterval is the number of headers between progress reports,
+// one difficulty epoch
+const defaultProgressInterval = 2016
+
+// HeaderStreamResult summarizes the headers validated by ValidateHeaderStream
+// Digest is the LE digest of the last header, and is zero if there are none.
+type HeaderStreamResult struct {
+	Headers         uint64        `json:"headers"`
+	TotalDifficulty Uint256       `json:"total_difficulty"`
+	Digest          Hash256Digest `json:"digest"`
+}
+
+// HeaderStreamOptions configures ValidateHeaderStream
+// If Progress is set, it is called with the running result after every
+// ProgressInterval headers, and after the last header. ProgressInterval
+// defaults to 2016.
+type HeaderStreamOptions struct {
+	Framing          HeaderFraming
+	Progress         func(HeaderStreamResult)
+	ProgressInterval uint64
+}
+
+// headerStream reads headers from a stream in a framing
+// remaining is the number of headers left in the current headers message.
+type headerStream struct {
+	r         *bufio.Reader
+	framing   HeaderFraming
+	remaining uint64
+}
+
+// readStreamVarInt reads a canonical VarInt
+// ok is false if the stream ends before the VarInt starts.
+func readStreamVarInt(r *bufio.Reader) (uint64, bool, error) {
+	flag, err := r.ReadByte()
+	if err == io.EOF {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	b := make([]byte, 1+DetermineVarIntDataLength(flag))
+	b[0] = flag
+	if _, err := io.ReadFull(r, b[1:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, false, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+		}
+		return 0, false, err
+	}
+
+	_, number, _ := ParseVarInt(b)
+	if len(encodeVarInt(number)) != len(b) {
+		return 0, false, NewSPVError(ErrCodeBadVarInt, "Non-canonical VarInt")
+	}
+	return number, true, nil
+}
+
+// next reads the next header into header
+// ok is false at the end of the stream.
+func (s *headerStream) next(header *RawHeader) (bool, error) {
+	switch s.framing {
+	case FramingRaw:
+		_, err := io.ReadFull(s.r, header[:])
+		if err == io.EOF {
+			return false, nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			return false, NewSPVError(ErrCodeBadLength, "Header bytes not multiple of 80")
+		}
+		return err == nil, err
+
+	case FramingHeadersMessage:
+		// Messages may hold no headers
+		for s.remaining == 0 {
+			count, ok, err := readStreamVarInt(s.r)
+			if !ok || err != nil {
+				return false, err
+			}
+			s.remaining = count
+		}
+
+		if _, err := io.ReadFull(s.r, header[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return false, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+			}
+			return false, err
+		}
+		txCount, ok, err := readStreamVarInt(s.r)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+		}
+		if txCount != 0 {
+			return false, NewSPVError(ErrCodeBadLength, "Headers message has a nonzero transaction count")
+		}
+		s.remaining--
+		return true, nil
+
+	default:
+		return false, newSPVErrorf(ErrCodeUnknown, "Unknown header framing %d", s.framing)
+	}
+}
+
+// drain reads raw headers to the end of the stream
+// It returns an error if the stream ends mid-header.
+func (s *headerStream) drain(ctx context.Context) error {
+	var header RawHeader
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ok, err := s.next(&header)
+		if !ok || err != nil {
+			return err
+		}
+	}
+}
+
+// ValidateHeaderStream checks validity of a header chain read from r
+// It makes the same checks as ValidateHeaderChain, and its TotalDifficulty
+// and errors are that function's results, but it holds only one header at a
+// time. As ValidateHeaderChain checks the length of the chain first, a raw
+// stream is read to its end after an invalid header, and is rejected if it
+// ends mid-header.
+//
+// Validation stops when ctx is done, returning ctx.Err(). On error, the result
+// covers the headers validated before the failure.
+func ValidateHeaderStream(ctx context.Context, r io.Reader, net *NetParams, opts HeaderStreamOptions) (HeaderStreamResult, error) {
+	interval := opts.ProgressInterval
+	if interval == 0 {
+		interval = defaultProgressInterval
+	}
+
+	stream := headerStream{r: bufio.NewReader(r), framing: opts.Framing}
+	result := HeaderStreamResult{}
+	var header RawHeader
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		ok, err := stream.next(&header)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			break
+		}
+
+		digest, difficulty, err := validateChainedHeader(header, result.Headers == 0, result.Digest, net)
+		if err != nil {
+			if opts.Framing == FramingRaw {
+				if lengthErr := stream.drain(ctx); lengthErr != nil {
+					return result, lengthErr
+				}
+			}
+			return result, err
+		}
+		result.Headers++
+		result.Digest = digest
+		result.TotalDifficulty = result.TotalDifficulty.Add(difficulty)
+
+		if opts.Progress != nil && result.Headers%interval == 0 {
+			opts.Progress(result)
+		}
+	}
+
+	if opts.Progress != nil && result.Headers%interval != 0 {
+		opts.Progress(result)
+	}
+	return result, nil
+}
@@ -0,0 +1,130 @@
+package btcspv_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// headersMessage frames raw headers as a headers message payload
+func headersMessage(headers []byte) []byte {
+	message := []byte{byte(len(headers) / 80)}
+	for i := 0; i < len(headers); i += 80 {
+		message = append(message, headers[i:i+80]...)
+		message = append(message, 0)
+	}
+	return message
+}
+
+func (suite *UtilsSuite) TestValidateHeaderStream() {
+	fixture := suite.Fixtures.ValidateHeaderChain
+
+	for i := range fixture {
+		headers := []byte(fixture[i].Input)
+		expected, err := btcspv.ValidateHeaderChain(headers, &btcspv.MainNetParams)
+		suite.Nil(err)
+
+		count := uint64(len(headers) / 80)
+		lastDigest := btcspv.Hash256(headers[len(headers)-80:])
+
+		reports := []btcspv.HeaderStreamResult{}
+		actual, err := btcspv.ValidateHeaderStream(
+			context.Background(),
+			bytes.NewReader(headers),
+			&btcspv.MainNetParams,
+			btcspv.HeaderStreamOptions{
+				Progress:         func(r btcspv.HeaderStreamResult) { reports = append(reports, r) },
+				ProgressInterval: 1,
+			})
+		suite.Nil(err)
+		suite.Equal(expected, actual.TotalDifficulty)
+		suite.Equal(count, actual.Headers)
+		suite.Equal(lastDigest, actual.Digest)
+		suite.Equal(int(count), len(reports))
+		suite.Equal(actual, reports[len(reports)-1])
+
+		// Split across messages, including an empty one
+		framed := append(headersMessage(headers[:80]), 0)
+		framed = append(framed, headersMessage(headers[80:])...)
+		actual, err = btcspv.ValidateHeaderStream(
+			context.Background(),
+			bytes.NewReader(framed),
+			&btcspv.MainNetParams,
+			btcspv.HeaderStreamOptions{Framing: btcspv.FramingHeadersMessage})
+		suite.Nil(err)
+		suite.Equal(expected, actual.TotalDifficulty)
+		suite.Equal(lastDigest, actual.Digest)
+	}
+
+	fixtureError := suite.Fixtures.ValidateHeaderChainError
+
+	for i := range fixtureError {
+		testCase := fixtureError[i]
+		_, err := btcspv.ValidateHeaderStream(
+			context.Background(),
+			bytes.NewReader(testCase.Input),
+			&btcspv.MainNetParams,
+			btcspv.HeaderStreamOptions{})
+		suite.EqualError(err, testCase.ErrorMessage)
+	}
+}
+
+func (suite *UtilsSuite) TestValidateHeaderStreamEmpty() {
+	actual, err := btcspv.ValidateHeaderStream(
+		context.Background(),
+		bytes.NewReader([]byte{}),
+		&btcspv.MainNetParams,
+		btcspv.HeaderStreamOptions{})
+	suite.Nil(err)
+	suite.Equal(btcspv.HeaderStreamResult{}, actual)
+}
+
+func (suite *UtilsSuite) TestValidateHeaderStreamFramingErrors() {
+	headers := []byte(suite.Fixtures.ValidateHeaderChain[0].Input)
+	framed := headersMessage(headers)
+
+	cases := []struct {
+		stream []byte
+		code   btcspv.SPVErrorCode
+	}{
+		// truncated mid-header
+		{framed[:100], btcspv.ErrCodeReadOverrun},
+		// missing the last transaction count
+		{framed[:len(framed)-1], btcspv.ErrCodeReadOverrun},
+		// nonzero transaction count
+		{append(append([]byte{1}, headers[:80]...), 1), btcspv.ErrCodeBadLength},
+		// non-canonical count
+		{append([]byte{0xfd, 1, 0}, framed[1:]...), btcspv.ErrCodeBadVarInt},
+	}
+
+	for i := range cases {
+		_, err := btcspv.ValidateHeaderStream(
+			context.Background(),
+			bytes.NewReader(cases[i].stream),
+			&btcspv.MainNetParams,
+			btcspv.HeaderStreamOptions{Framing: btcspv.FramingHeadersMessage})
+
+		var spvErr *btcspv.SPVError
+		suite.True(errors.As(err, &spvErr))
+		suite.Equal(cases[i].code, spvErr.Code)
+	}
+}
+
+func (suite *UtilsSuite) TestValidateHeaderStreamCancel() {
+	headers := []byte(suite.Fixtures.ValidateHeaderChain[0].Input)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Cancel after the first header
+	actual, err := btcspv.ValidateHeaderStream(
+		ctx,
+		bytes.NewReader(headers),
+		&btcspv.MainNetParams,
+		btcspv.HeaderStreamOptions{
+			Progress:         func(btcspv.HeaderStreamResult) { cancel() },
+			ProgressInterval: 1,
+		})
+	suite.True(errors.Is(err, context.Canceled))
+	suite.Equal(uint64(1), actual.Headers)
+}
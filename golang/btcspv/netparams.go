@@ -0,0 +1,129 @@
+package btcspv
+
+import "fmt"
+
+// NetParams holds the parameters of a Bitcoin network that affect address
+// encoding and header validation
+type NetParams struct {
+	Name string
+
+	// PubKeyHashAddrID and ScriptHashAddrID are the base58check version bytes
+	// of p2pkh and p2sh addresses. Bech32HRP is the human-readable part of
+	// segwit addresses.
+	PubKeyHashAddrID byte
+	ScriptHashAddrID byte
+	Bech32HRP        string
+
+	// PowLimit is the highest target allowed. PowLimitBits is its compact
+	// encoding, used by min-difficulty blocks.
+	PowLimit     Uint256
+	PowLimitBits uint32
+
+	// DiffOneTarget is the target of a difficulty 1 header. It is 0x1d00ffff
+	// on every built-in network, as in Core's getdifficulty, so headers easier
+	// than it, e.g. on regtest, have difficulty 0. Measure them with
+	// CalculateWork instead.
+	DiffOneTarget Uint256
+
+	// RetargetInterval is the number of headers in a difficulty epoch, and
+	// TargetSpacing is the expected number of seconds between headers
+	RetargetInterval uint32
+	TargetSpacing    uint64
+
+	// AllowMinDifficultyBlocks allows a header to use PowLimitBits if its
+	// timestamp is more than twice TargetSpacing after its parent's.
+	// NoRetargeting keeps the target constant across epochs. EnforceBIP94
+	// retargets from the first header of the epoch, rather than the last.
+	AllowMinDifficultyBlocks bool
+	NoRetargeting            bool
+	EnforceBIP94             bool
+}
+
+// mainPowLimit is the pow limit of mainnet and testnet
+var mainPowLimit = Uint256{^uint64(0), ^uint64(0), ^uint64(0), 0x00000000ffffffff}
+
+// MainNetParams are the parameters of Bitcoin mainnet
+var MainNetParams = NetParams{
+	Name:             "mainnet",
+	PubKeyHashAddrID: 0x00,
+	ScriptHashAddrID: 0x05,
+	Bech32HRP:        "bc",
+	PowLimit:         mainPowLimit,
+	PowLimitBits:     0x1d00ffff,
+	DiffOneTarget:    diffOneTarget,
+	RetargetInterval: 2016,
+	TargetSpacing:    600,
+}
+
+// TestNet3Params are the parameters of testnet3
+var TestNet3Params = NetParams{
+	Name:                     "testnet3",
+	PubKeyHashAddrID:         0x6f,
+	ScriptHashAddrID:         0xc4,
+	Bech32HRP:                "tb",
+	PowLimit:                 mainPowLimit,
+	PowLimitBits:             0x1d00ffff,
+	DiffOneTarget:            diffOneTarget,
+	RetargetInterval:         2016,
+	TargetSpacing:            600,
+	AllowMinDifficultyBlocks: true,
+}
+
+// TestNet4Params are the parameters of the BIP94 testnet4
+var TestNet4Params = NetParams{
+	Name:                     "testnet4",
+	PubKeyHashAddrID:         0x6f,
+	ScriptHashAddrID:         0xc4,
+	Bech32HRP:                "tb",
+	PowLimit:                 mainPowLimit,
+	PowLimitBits:             0x1d00ffff,
+	DiffOneTarget:            diffOneTarget,
+	RetargetInterval:         2016,
+	TargetSpacing:            600,
+	AllowMinDifficultyBlocks: true,
+	EnforceBIP94:             true,
+}
+
+// SigNetParams are the parameters of the default signet
+var SigNetParams = NetParams{
+	Name:             "signet",
+	PubKeyHashAddrID: 0x6f,
+	ScriptHashAddrID: 0xc4,
+	Bech32HRP:        "tb",
+	PowLimit:         Uint256{0, 0, 0, 0x00000377ae000000},
+	PowLimitBits:     0x1e0377ae,
+	DiffOneTarget:    diffOneTarget,
+	RetargetInterval: 2016,
+	TargetSpacing:    600,
+}
+
+// RegTestParams are the parameters of regtest
+var RegTestParams = NetParams{
+	Name:                     "regtest",
+	PubKeyHashAddrID:         0x6f,
+	ScriptHashAddrID:         0xc4,
+	Bech32HRP:                "bcrt",
+	PowLimit:                 Uint256{0, 0, 0, 0x7fffff0000000000},
+	PowLimitBits:             0x207fffff,
+	DiffOneTarget:            diffOneTarget,
+	RetargetInterval:         2016,
+	TargetSpacing:            600,
+	AllowMinDifficultyBlocks: true,
+	NoRetargeting:            true,
+}
+
+// NetParamsByName returns the built-in parameters of a network by name
+// Known names are mainnet, testnet3, testnet4, signet and regtest.
+func NetParamsByName(name string) (*NetParams, error) {
+	for _, net := range []*NetParams{&MainNetParams, &TestNet3Params, &TestNet4Params, &SigNetParams, &RegTestParams} {
+		if net.Name == name {
+			return net, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown network: %s", name)
+}
+
+// TargetTimespan returns the expected number of seconds in a difficulty epoch
+func (n *NetParams) TargetTimespan() uint64 {
+	return uint64(n.RetargetInterval) * n.TargetSpacing
+}
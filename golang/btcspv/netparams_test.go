@@ -0,0 +1,160 @@
+package btcspv_test
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/btcsuite/btcutil/base58"
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// mineHeader builds a header on prev with nBits bits, and a valid nonce
+func mineHeader(prev Hash256Digest, merkleRoot Hash256Digest, timestamp uint32, bits uint32) btcspv.RawHeader {
+	var raw btcspv.RawHeader
+	binary.LittleEndian.PutUint32(raw[0:4], 2)
+	copy(raw[4:36], prev[:])
+	copy(raw[36:68], merkleRoot[:])
+	binary.LittleEndian.PutUint32(raw[68:72], timestamp)
+	binary.LittleEndian.PutUint32(raw[72:76], bits)
+
+	target := btcspv.ExtractTarget(raw)
+	for nonce := uint32(0); ; nonce++ {
+		binary.LittleEndian.PutUint32(raw[76:80], nonce)
+		if btcspv.ValidateHeaderWork(btcspv.Hash256(raw[:]), target) {
+			return raw
+		}
+	}
+}
+
+func (suite *UtilsSuite) TestNetParamsByName() {
+	for _, name := range []string{"mainnet", "testnet3", "testnet4", "signet", "regtest"} {
+		net, err := btcspv.NetParamsByName(name)
+		suite.Nil(err)
+		suite.Equal(name, net.Name)
+		suite.Equal(uint64(1209600), net.TargetTimespan())
+
+		suite.Equal(net.PowLimitBits, btcspv.TargetToCompact(net.PowLimit, false))
+	}
+
+	_, err := btcspv.NetParamsByName("testnet")
+	suite.EqualError(err, "Unknown network: testnet")
+}
+
+func (suite *UtilsSuite) TestEncodeAddressNetParams() {
+	pkh := decodeHex("751e76e8199196d454941c45d1b3a323f1433bd6")
+
+	addr, err := btcspv.EncodeP2WPKH(pkh, &btcspv.TestNet3Params)
+	suite.Nil(err)
+	suite.Equal("tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", addr)
+
+	addr, err = btcspv.EncodeP2WPKH(pkh, &btcspv.RegTestParams)
+	suite.Nil(err)
+	suite.Equal("bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", addr)
+
+	addr, err = btcspv.EncodeP2PKH(pkh, &btcspv.SigNetParams)
+	suite.Nil(err)
+	payload, version, err := base58.CheckDecode(addr)
+	suite.Nil(err)
+	suite.Equal(byte(0x6f), version)
+	suite.Equal(pkh, payload)
+
+	addr, err = btcspv.EncodeP2SH(pkh, &btcspv.TestNet4Params)
+	suite.Nil(err)
+	_, version, _ = base58.CheckDecode(addr)
+	suite.Equal(byte(0xc4), version)
+}
+
+func (suite *UtilsSuite) TestCalculateDifficultyNetParams() {
+	// Every network measures difficulty against 0x1d00ffff, as Core does
+	diffOne, _, _ := btcspv.CompactToTarget(0x1d00ffff)
+	for _, name := range []string{"mainnet", "testnet3", "testnet4", "signet", "regtest"} {
+		net, _ := btcspv.NetParamsByName(name)
+		suite.Equal(btcspv.NewUint256(1), btcspv.CalculateDifficulty(diffOne, net))
+	}
+
+	// Regtest headers have no difficulty, but they have work
+	limit := btcspv.RegTestParams.PowLimit
+	suite.Equal(Uint256{}, btcspv.CalculateDifficulty(limit, &btcspv.RegTestParams))
+	suite.Equal(btcspv.NewUint256(2), btcspv.CalculateWork(limit))
+}
+
+func (suite *UtilsSuite) TestValidateHeaderChainMinDifficulty() {
+	// A testnet-like network with short epochs, and a pow limit low enough to
+	// mine in tests. Retargeting the pow limit must not overflow 256 bits.
+	net := btcspv.TestNet3Params
+	net.PowLimitBits = 0x2007ffff
+	net.PowLimit, _, _ = btcspv.CompactToTarget(net.PowLimitBits)
+	net.RetargetInterval = 4
+	net.TargetSpacing = 1
+
+	epochBits := uint32(0x2000ffff)
+	epochTarget, _, _ := btcspv.CompactToTarget(epochBits)
+	anchor := btcspv.ChainAnchor{
+		Digest:      Hash256Digest{1},
+		Height:      1000,
+		Timestamp:   1600000000,
+		EpochStart:  1600000000,
+		EpochTarget: epochTarget,
+	}
+
+	chain := func(times []uint, bits []uint32) []byte {
+		headers := []byte{}
+		prev := anchor.Digest
+		for i := range times {
+			raw := mineHeader(prev, Hash256Digest{}, uint32(anchor.Timestamp+times[i]), bits[i])
+			prev = btcspv.Hash256(raw[:])
+			headers = append(headers, raw[:]...)
+		}
+		return headers
+	}
+
+	// After a gap of more than twice the spacing, the next header must be a
+	// min-difficulty header. The one after it returns to the epoch target.
+	headers := chain([]uint{1, 4, 5}, []uint32{epochBits, net.PowLimitBits, epochBits})
+	_, err := btcspv.ValidateHeaderChainRetarget(anchor, headers, &net)
+	suite.Nil(err)
+
+	// Mainnet rules forbid the min-difficulty header
+	_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &btcspv.MainNetParams)
+	suite.True(errors.Is(err, btcspv.ErrUnexpectedDifficultyChange))
+
+	headers = chain([]uint{1, 2}, []uint32{epochBits, net.PowLimitBits})
+	_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &net)
+	suite.True(errors.Is(err, btcspv.ErrUnexpectedDifficultyChange))
+
+	headers = chain([]uint{1, 4}, []uint32{epochBits, epochBits})
+	_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &net)
+	suite.True(errors.Is(err, btcspv.ErrUnexpectedDifficultyChange))
+
+	// testnet3 retargets from a trailing min-difficulty header, so the next
+	// epoch starts at the pow limit. BIP94 retargets from the epoch target.
+	anchor.Height = 1002
+	anchor.EpochStart = anchor.Timestamp + 4 - uint(net.TargetTimespan())
+	headers = chain([]uint{4, 5}, []uint32{net.PowLimitBits, net.PowLimitBits})
+	_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &net)
+	suite.Nil(err)
+
+	// The anchor may itself be the trailing min-difficulty header
+	minAnchor := anchor
+	minAnchor.Height = 1003
+	minAnchor.Bits = net.PowLimitBits
+	minAnchor.EpochStart = minAnchor.Timestamp - uint(net.TargetTimespan())
+	_, err = btcspv.ValidateHeaderChainRetarget(minAnchor, chain([]uint{1}, []uint32{net.PowLimitBits}), &net)
+	suite.Nil(err)
+	minAnchor.Bits = 0
+	_, err = btcspv.ValidateHeaderChainRetarget(minAnchor, chain([]uint{1}, []uint32{net.PowLimitBits}), &net)
+	suite.True(errors.Is(err, btcspv.ErrUnexpectedDifficultyChange))
+
+	net.EnforceBIP94 = true
+	_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &net)
+	suite.True(errors.Is(err, btcspv.ErrUnexpectedDifficultyChange))
+	headers = chain([]uint{4, 5}, []uint32{net.PowLimitBits, epochBits})
+	_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &net)
+	suite.Nil(err)
+
+	// Regtest never retargets
+	anchor.Height = 2014
+	headers = chain([]uint{1, 2}, []uint32{epochBits, epochBits})
+	_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &btcspv.RegTestParams)
+	suite.Nil(err)
+}
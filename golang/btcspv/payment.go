@@ -0,0 +1,95 @@
+package btcspv
+
+import (
+	"bytes"
+	"math"
+)
+
+// PaymentRequirement describes the payment that an SPVProof must show
+// ScriptPubkey is the expected output script, without its length prefix. Use
+// DecodeAddress(addr, net).ScriptPubkey() to require payment to an address.
+// MinWork is accumulated work, summed by CalculateWork as in Core's chainwork.
+// MinWork is required, as the headers are not checked against a trusted
+// anchor. A zero MinConfirmations sets no requirement.
+type PaymentRequirement struct {
+	ScriptPubkey     HexBytes `json:"script_pubkey"`
+	MinValue         uint64   `json:"min_value"`
+	MinWork          Uint256  `json:"min_work"`
+	MinConfirmations uint32   `json:"min_confirmations"`
+}
+
+// PaymentVerdict is the result of evaluating a payment
+// OutputIndices are the outputs that pay ScriptPubkey, and Value is their
+// total value. Work and Confirmations count the confirming header.
+type PaymentVerdict struct {
+	OutputIndices []uint  `json:"output_indices"`
+	Value         uint64  `json:"value"`
+	Work          Uint256 `json:"work"`
+	Confirmations uint32  `json:"confirmations"`
+}
+
+// EvaluatePayment checks that a proof shows a payment meeting a requirement
+// headers is a chain of headers built on the proof's ConfirmingHeader, not
+// including it. It is checked with ValidateHeaderChain, so retargets are not
+// checked, and every header's target must be at most net.PowLimit. As anyone
+// can mine headers at the pow limit, req.MinWork must be nonzero, and should
+// be set from the work an attacker cannot afford. The value paid is summed
+// across all outputs paying ScriptPubkey.
+//
+// Errors identify the first failing condition, in this order: a zero MinWork
+// (ErrInsufficientWork), the proof (e.g. ErrBadMerkleProof), the header chain
+// (e.g. ErrInvalidChain, or ErrLowWork for a target above the pow limit),
+// ErrWrongPayee, ErrInsufficientValue, ErrInsufficientConfirmations and
+// ErrInsufficientWork. Compare them with errors.Is. The verdict is filled in
+// as far as evaluation got.
+func EvaluatePayment(proof SPVProof, headers []byte, req PaymentRequirement, net *NetParams) (PaymentVerdict, error) {
+	verdict := PaymentVerdict{OutputIndices: []uint{}}
+
+	if req.MinWork.IsZero() {
+		return verdict, NewSPVError(ErrCodeInsufficientWork, "MinWork must be nonzero")
+	}
+
+	if _, err := proof.Validate(); err != nil {
+		return verdict, err
+	}
+
+	chain := append(proof.ConfirmingHeader.Raw[:], headers...)
+	if _, err := ValidateHeaderChain(chain, net); err != nil {
+		return verdict, err
+	}
+	for i := 0; i < len(chain); i += 80 {
+		header, _ := NewRawHeader(chain[i : i+80])
+		target := ExtractTarget(header)
+		if target.GT(net.PowLimit) {
+			return PaymentVerdict{OutputIndices: []uint{}}, newSPVErrorf(ErrCodeLowWork, "Header %d target exceeds the pow limit", i/80)
+		}
+		verdict.Work = verdict.Work.Add(CalculateWork(target))
+	}
+	verdict.Confirmations = uint32(len(chain) / 80)
+
+	outputs, err := parseOutputs(proof.Vout)
+	if err != nil {
+		return verdict, err
+	}
+	for i := range outputs {
+		if bytes.Equal(outputs[i].ScriptPubkey, req.ScriptPubkey) {
+			verdict.OutputIndices = append(verdict.OutputIndices, uint(i))
+			if outputs[i].Value > math.MaxUint64-verdict.Value {
+				return verdict, NewSPVError(ErrCodeMalformedOutput, "Outputs paying the script overflow a uint64")
+			}
+			verdict.Value += outputs[i].Value
+		}
+	}
+
+	switch {
+	case len(verdict.OutputIndices) == 0:
+		return verdict, NewSPVError(ErrCodeWrongPayee, "No output pays the expected script")
+	case verdict.Value < req.MinValue:
+		return verdict, newSPVErrorf(ErrCodeInsufficientValue, "Outputs pay %d, expected at least %d", verdict.Value, req.MinValue)
+	case verdict.Confirmations < req.MinConfirmations:
+		return verdict, newSPVErrorf(ErrCodeInsufficientConfirmations, "Payment has %d confirmations, expected at least %d", verdict.Confirmations, req.MinConfirmations)
+	case verdict.Work.LT(req.MinWork):
+		return verdict, newSPVErrorf(ErrCodeInsufficientWork, "Headers have %s work, expected at least %s", verdict.Work, req.MinWork)
+	}
+	return verdict, nil
+}
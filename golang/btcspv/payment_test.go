@@ -0,0 +1,127 @@
+package btcspv_test
+
+import (
+	"encoding/binary"
+	"errors"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// regtestPayment mines the valid test proof's transaction into a regtest
+// header, and n headers on top of it
+func (suite *TypesSuite) regtestPayment(n int) (SPVProof, []byte) {
+	return minePayment(suite.ValidProofs[0], n)
+}
+
+// minePayment mines a proof's transaction into a regtest header, and n
+// headers on top of it
+func minePayment(proof SPVProof, n int) (SPVProof, []byte) {
+	txids := []Hash256Digest{{1}, proof.TxID}
+	intermediateNodes, root, _ := btcspv.BuildMerkleProof(txids, 1)
+
+	bits := btcspv.RegTestParams.PowLimitBits
+	raw := mineHeader(Hash256Digest{}, root, 1600000000, bits)
+	proof.ConfirmingHeader = btcspv.HeaderFromRaw(raw, 1000)
+	proof.IntermediateNodes = intermediateNodes
+	proof.Index = 1
+
+	headers := []byte{}
+	prev := proof.ConfirmingHeader.Hash
+	for i := 0; i < n; i++ {
+		raw = mineHeader(prev, Hash256Digest{}, 1600000600+uint32(i)*600, bits)
+		prev = btcspv.Hash256(raw[:])
+		headers = append(headers, raw[:]...)
+	}
+	return proof, headers
+}
+
+func (suite *TypesSuite) TestEvaluatePayment() {
+	proof, headers := suite.regtestPayment(5)
+	tx, _ := btcspv.NewTransaction(btcspv.BlockTx{
+		Version: proof.Version, Vin: proof.Vin, Vout: proof.Vout, Locktime: proof.Locktime})
+	out := tx.Outputs[0]
+	net := &btcspv.RegTestParams
+
+	req := btcspv.PaymentRequirement{
+		ScriptPubkey:     out.ScriptPubkey,
+		MinValue:         out.Value,
+		MinWork:          btcspv.NewUint256(12),
+		MinConfirmations: 6,
+	}
+	verdict, err := btcspv.EvaluatePayment(proof, headers, req, net)
+	suite.Nil(err)
+	suite.Equal([]uint{0}, verdict.OutputIndices)
+	suite.Equal(out.Value, verdict.Value)
+	suite.Equal(uint32(6), verdict.Confirmations)
+	suite.Equal(btcspv.NewUint256(12), verdict.Work)
+
+	// Each failing condition is reported, with the verdict filled in
+	wrongPayee := req
+	wrongPayee.ScriptPubkey = append(HexBytes{}, out.ScriptPubkey...)
+	wrongPayee.ScriptPubkey[len(out.ScriptPubkey)-1] ^= 1
+	verdict, err = btcspv.EvaluatePayment(proof, headers, wrongPayee, net)
+	suite.True(errors.Is(err, btcspv.ErrWrongPayee))
+	suite.Equal([]uint{}, verdict.OutputIndices)
+	suite.Equal(uint32(6), verdict.Confirmations)
+
+	tooMuch := req
+	tooMuch.MinValue = out.Value + 1
+	verdict, err = btcspv.EvaluatePayment(proof, headers, tooMuch, net)
+	suite.True(errors.Is(err, btcspv.ErrInsufficientValue))
+	suite.Equal(out.Value, verdict.Value)
+
+	_, err = btcspv.EvaluatePayment(proof, headers[:80*4], req, net)
+	suite.True(errors.Is(err, btcspv.ErrInsufficientConfirmations))
+
+	noConfirmations := req
+	noConfirmations.MinConfirmations = 0
+	_, err = btcspv.EvaluatePayment(proof, headers[:80*4], noConfirmations, net)
+	suite.True(errors.Is(err, btcspv.ErrInsufficientWork))
+	suite.EqualError(err, "Headers have 10 work, expected at least 12")
+
+	// Invalid proofs and header chains
+	badProof := proof
+	badProof.Index = 0
+	_, err = btcspv.EvaluatePayment(badProof, headers, req, net)
+	suite.True(errors.Is(err, btcspv.ErrBadMerkleProof))
+
+	_, err = btcspv.EvaluatePayment(proof, headers[80:], req, net)
+	suite.True(errors.Is(err, btcspv.ErrInvalidChain))
+	_, err = btcspv.EvaluatePayment(proof, headers[1:], req, net)
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+
+	// Regtest headers are above the mainnet pow limit
+	_, err = btcspv.EvaluatePayment(proof, headers, req, &btcspv.MainNetParams)
+	suite.True(errors.Is(err, btcspv.ErrLowWork))
+	suite.EqualError(err, "Header 0 target exceeds the pow limit")
+
+	// Work is always required
+	noWork := req
+	noWork.MinWork = btcspv.Uint256{}
+	_, err = btcspv.EvaluatePayment(proof, headers, noWork, net)
+	suite.True(errors.Is(err, btcspv.ErrInsufficientWork))
+}
+
+func (suite *TypesSuite) TestEvaluatePaymentValueOverflow() {
+	// Two outputs paying the same script, whose values sum past 2^64
+	script := HexBytes{0x00, 0x14}
+	script = append(script, make([]byte, 20)...)
+	output := make([]byte, 8)
+	binary.LittleEndian.PutUint64(output, 1<<63)
+	output = append(output, byte(len(script)))
+	output = append(output, script...)
+
+	proof := suite.ValidProofs[0]
+	proof.Vout = append(append(HexBytes{0x02}, output...), output...)
+	proof.TxID = btcspv.CalculateTxID(proof.Version, proof.Vin, proof.Vout, proof.Locktime)
+	proof, headers := minePayment(proof, 1)
+
+	req := btcspv.PaymentRequirement{
+		ScriptPubkey: script,
+		MinValue:     1,
+		MinWork:      btcspv.NewUint256(1),
+	}
+	_, err := btcspv.EvaluatePayment(proof, headers, req, &btcspv.RegTestParams)
+	suite.True(errors.Is(err, btcspv.ErrMalformedOutput))
+	suite.EqualError(err, "Outputs paying the script overflow a uint64")
+}
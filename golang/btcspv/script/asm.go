@@ -0,0 +1,60 @@
+package script
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Disasm renders a script as ASM, like Core's ScriptToAsmStr
+// Pushes of up to 4 bytes are shown as script numbers, and longer pushes as
+// hex. A malformed push ends the output with "[error]".
+func Disasm(script []byte) string {
+	parts := []string{}
+	t := NewTokenizer(script)
+	for t.Next() {
+		op := t.Op()
+		switch {
+		case op.Opcode > OP_PUSHDATA4 || op.Opcode == OP_0:
+			parts = append(parts, OpcodeName(op.Opcode))
+		case len(op.Data) <= 4:
+			parts = append(parts, strconv.FormatInt(scriptNum(op.Data), 10))
+		default:
+			parts = append(parts, hex.EncodeToString(op.Data))
+		}
+	}
+	if t.Err() != nil {
+		parts = append(parts, "[error]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// scriptNum decodes a little-endian sign-magnitude number of up to 8 bytes
+func scriptNum(data []byte) int64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var n int64
+	for i, b := range data {
+		n |= int64(b) << (8 * uint(i))
+	}
+	last := data[len(data)-1]
+	if last&0x80 != 0 {
+		return -(n &^ (int64(0x80) << (8 * uint(len(data)-1))))
+	}
+	return n
+}
+
+// isMinimalScriptNum returns true if data is the shortest encoding of its
+// number, as CScriptNum requires with fRequireMinimal
+func isMinimalScriptNum(data []byte) bool {
+	if len(data) == 0 {
+		return true
+	}
+	// The last byte may only be 0x00 or 0x80 if it is needed for the sign bit
+	last := data[len(data)-1]
+	if last&0x7f == 0 {
+		return len(data) > 1 && data[len(data)-2]&0x80 != 0
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package script
+
+// Class is the standard template that an output script matches
+type Class string
+
+// Script classes, named as in Core's decodescript
+const (
+	NonStandard         Class = "nonstandard"
+	PubKey              Class = "pubkey"
+	PubKeyHash          Class = "pubkeyhash"
+	ScriptHash          Class = "scripthash"
+	MultiSig            Class = "multisig"
+	NullData            Class = "nulldata"
+	WitnessV0KeyHash    Class = "witness_v0_keyhash"
+	WitnessV0ScriptHash Class = "witness_v0_scripthash"
+	WitnessV1Taproot    Class = "witness_v1_taproot"
+	WitnessUnknown      Class = "witness_unknown"
+)
+
+// maxPubkeysPerMultisig is the most keys a bare multisig script may have
+const maxPubkeysPerMultisig = 20
+
+// String returns the name of the class
+func (c Class) String() string {
+	return string(c)
+}
+
+// Classify matches a script against the standard templates, like Core's Solver
+// It also returns the solutions of the template:
+//   - pubkey: the pubkey
+//   - pubkeyhash, scripthash: the hash
+//   - witness_v0_*, witness_v1_taproot: the witness program
+//   - witness_unknown: the version, as one byte, then the witness program
+//   - multisig: m as one byte, the pubkeys, then n as one byte
+//   - nulldata: the data of each push after OP_RETURN
+func Classify(script []byte) (Class, [][]byte) {
+	if IsP2SH(script) {
+		return ScriptHash, [][]byte{script[2:22]}
+	}
+
+	if version, program, ok := WitnessProgram(script); ok {
+		switch {
+		case version == 0 && len(program) == 20:
+			return WitnessV0KeyHash, [][]byte{program}
+		case version == 0 && len(program) == 32:
+			return WitnessV0ScriptHash, [][]byte{program}
+		case version == 1 && len(program) == 32:
+			return WitnessV1Taproot, [][]byte{program}
+		case version != 0:
+			return WitnessUnknown, [][]byte{{byte(version)}, program}
+		}
+		return NonStandard, nil
+	}
+
+	if IsNullData(script) {
+		data, _ := PushedData(script[1:])
+		return NullData, data
+	}
+
+	if pubkey, ok := matchP2PK(script); ok {
+		return PubKey, [][]byte{pubkey}
+	}
+
+	if len(script) == 25 && script[0] == OP_DUP && script[1] == OP_HASH160 &&
+		script[2] == 20 && script[23] == OP_EQUALVERIFY && script[24] == OP_CHECKSIG {
+		return PubKeyHash, [][]byte{script[3:23]}
+	}
+
+	if solutions, ok := matchMultisig(script); ok {
+		return MultiSig, solutions
+	}
+
+	return NonStandard, nil
+}
+
+// IsP2SH returns true if a script is OP_HASH160 <20 bytes> OP_EQUAL
+func IsP2SH(script []byte) bool {
+	return len(script) == 23 && script[0] == OP_HASH160 && script[1] == 20 && script[22] == OP_EQUAL
+}
+
+// WitnessProgram returns the version and program of a witness output script
+// The script must be OP_0 or OP_1 to OP_16, then a direct push of 2 to 40
+// bytes, as in BIP141.
+func WitnessProgram(script []byte) (int, []byte, bool) {
+	if len(script) < 4 || len(script) > 42 {
+		return 0, nil, false
+	}
+	if script[0] != OP_0 && (script[0] < OP_1 || script[0] > OP_16) {
+		return 0, nil, false
+	}
+	if int(script[1])+2 != len(script) {
+		return 0, nil, false
+	}
+	version, _ := DecodeSmallInt(script[0])
+	return version, script[2:], true
+}
+
+// validPubkeySize returns true if a pubkey's length matches its prefix byte
+func validPubkeySize(pubkey []byte) bool {
+	if len(pubkey) == 0 {
+		return false
+	}
+	switch pubkey[0] {
+	case 0x02, 0x03:
+		return len(pubkey) == 33
+	case 0x04, 0x06, 0x07:
+		return len(pubkey) == 65
+	}
+	return false
+}
+
+// matchP2PK matches <pubkey> OP_CHECKSIG
+func matchP2PK(script []byte) ([]byte, bool) {
+	if (len(script) == 35 || len(script) == 67) && int(script[0]) == len(script)-2 &&
+		script[len(script)-1] == OP_CHECKSIG && validPubkeySize(script[1:len(script)-1]) {
+		return script[1 : len(script)-1], true
+	}
+	return nil, false
+}
+
+// multisigCount decodes m or n of a multisig script, like Core's
+// GetScriptNumber. Counts above 16 must be minimal pushes of minimally
+// encoded numbers.
+func multisigCount(op Op) (int, bool) {
+	count, ok := DecodeSmallInt(op.Opcode)
+	if !ok {
+		if op.Opcode > OP_PUSHDATA4 || !op.IsMinimalPush() || len(op.Data) > 4 || !isMinimalScriptNum(op.Data) {
+			return 0, false
+		}
+		count = int(scriptNum(op.Data))
+	}
+	return count, count >= 1 && count <= maxPubkeysPerMultisig
+}
+
+// matchMultisig matches <m> <pubkey>... <n> OP_CHECKMULTISIG
+func matchMultisig(script []byte) ([][]byte, bool) {
+	if len(script) < 1 || script[len(script)-1] != OP_CHECKMULTISIG {
+		return nil, false
+	}
+
+	ops, err := Parse(script)
+	if err != nil || len(ops) < 4 {
+		return nil, false
+	}
+	m, ok := multisigCount(ops[0])
+	if !ok {
+		return nil, false
+	}
+
+	keys := [][]byte{}
+	i := 1
+	for ; i < len(ops) && ops[i].Opcode <= OP_PUSHDATA4 && validPubkeySize(ops[i].Data); i++ {
+		keys = append(keys, ops[i].Data)
+	}
+	if i != len(ops)-2 {
+		return nil, false
+	}
+	n, ok := multisigCount(ops[i])
+	if !ok || n != len(keys) || m > n {
+		return nil, false
+	}
+
+	solutions := [][]byte{{byte(m)}}
+	solutions = append(solutions, keys...)
+	return append(solutions, []byte{byte(n)}), true
+}
+
+// IsNullData returns true if a script is OP_RETURN followed only by pushes
+func IsNullData(script []byte) bool {
+	return len(script) >= 1 && script[0] == OP_RETURN && IsPushOnly(script[1:])
+}
@@ -0,0 +1,106 @@
+package script_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/summa-tx/bitcoin-spv/golang/btcspv/script"
+)
+
+func TestClassify(t *testing.T) {
+	hash20 := "751e76e8199196d454941c45d1b3a323f1433bd6"
+	hash32 := "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"
+	compressed := "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	uncompressed := "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" +
+		"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+
+	cases := []struct {
+		script    string
+		class     script.Class
+		solutions []string
+	}{
+		{"76a914" + hash20 + "88ac", script.PubKeyHash, []string{hash20}},
+		{"a914" + hash20 + "87", script.ScriptHash, []string{hash20}},
+		{"0014" + hash20, script.WitnessV0KeyHash, []string{hash20}},
+		{"0020" + hash32, script.WitnessV0ScriptHash, []string{hash32}},
+		{"5120" + hash32, script.WitnessV1Taproot, []string{hash32}},
+		{"5214" + hash20, script.WitnessUnknown, []string{"02", hash20}},
+		{"6002751e", script.WitnessUnknown, []string{"10", "751e"}},
+		{"5121" + hash32 + "00", script.WitnessUnknown, []string{"01", hash32 + "00"}},
+		{"21" + compressed + "ac", script.PubKey, []string{compressed}},
+		{"41" + uncompressed + "ac", script.PubKey, []string{uncompressed}},
+		{
+			"5121" + compressed + "41" + uncompressed + "52ae",
+			script.MultiSig,
+			[]string{"01", compressed, uncompressed, "02"},
+		},
+		{"6a", script.NullData, []string{}},
+		{"6a0b68656c6c6f20776f726c64", script.NullData, []string{"68656c6c6f20776f726c64"}},
+		{"6a0201024c0103", script.NullData, []string{"0102", "03"}},
+		{"6a5104deadbeef", script.NullData, []string{"deadbeef"}},
+
+		// Near misses
+		{"0013" + hash20[:38], script.NonStandard, nil},
+		{"0015" + hash20 + "00", script.NonStandard, nil},
+		{"76a914" + hash20 + "88ad", script.NonStandard, nil},
+		{"a914" + hash20 + "88", script.NonStandard, nil},
+		{"21" + "05" + compressed[2:] + "ac", script.NonStandard, nil},
+		{"6a76", script.NonStandard, nil},
+		{"6a4c", script.NonStandard, nil},
+		{"5121" + compressed + "52ae", script.NonStandard, nil},
+		{"5221" + compressed + "51ae", script.NonStandard, nil},
+		{"0021" + compressed + "51ae", script.NonStandard, nil},
+		{"5121" + compressed + "51ae00", script.NonStandard, nil},
+		{"", script.NonStandard, nil},
+	}
+
+	for _, c := range cases {
+		class, solutions := script.Classify(decodeHex(c.script))
+		assert.Equal(t, c.class, class, c.script)
+		if c.solutions == nil {
+			assert.Nil(t, solutions, c.script)
+			continue
+		}
+		expected := [][]byte{}
+		for _, s := range c.solutions {
+			expected = append(expected, decodeHex(s))
+		}
+		assert.Equal(t, expected, solutions, c.script)
+	}
+}
+
+func TestClassifyMultisigPushedCount(t *testing.T) {
+	// Counts above 16 are pushed as script numbers
+	key := "21" + "02" + strings.Repeat("11", 32)
+	s := "0111" + strings.Repeat(key, 17) + "0111" + "ae"
+	class, solutions := script.Classify(decodeHex(s))
+	assert.Equal(t, script.MultiSig, class)
+	assert.Len(t, solutions, 19)
+	assert.Equal(t, []byte{17}, solutions[0])
+
+	// A non-minimal count is rejected
+	s = "4c0111" + strings.Repeat(key, 17) + "0111" + "ae"
+	class, _ = script.Classify(decodeHex(s))
+	assert.Equal(t, script.NonStandard, class)
+
+	// As is a count with a needless zero byte
+	s = "021100" + strings.Repeat(key, 17) + "0111" + "ae"
+	class, _ = script.Classify(decodeHex(s))
+	assert.Equal(t, script.NonStandard, class)
+	s = "0111" + strings.Repeat(key, 17) + "021100" + "ae"
+	class, _ = script.Classify(decodeHex(s))
+	assert.Equal(t, script.NonStandard, class)
+}
+
+func TestWitnessProgram(t *testing.T) {
+	version, program, ok := script.WitnessProgram(decodeHex("5120" + strings.Repeat("00", 32)))
+	assert.True(t, ok)
+	assert.Equal(t, 1, version)
+	assert.Len(t, program, 32)
+
+	_, _, ok = script.WitnessProgram(decodeHex("4f02abcd"))
+	assert.False(t, ok)
+	_, _, ok = script.WitnessProgram(decodeHex("0029" + strings.Repeat("00", 41)))
+	assert.False(t, ok)
+}
@@ -0,0 +1,8 @@
+package script
+
+import "errors"
+
+// Errors returned while parsing scripts
+var (
+	ErrTruncatedPush = errors.New("Push extends past the end of the script")
+)
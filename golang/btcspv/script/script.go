@@ -0,0 +1,171 @@
+package script
+
+import "encoding/binary"
+
+// Op is a single operation of a script
+// Data holds the bytes pushed by push opcodes, and is nil for other opcodes.
+type Op struct {
+	Opcode byte
+	Data   []byte
+}
+
+// IsPush returns true if the op pushes data or a small integer
+// Like Core's IsPushOnly, OP_RESERVED counts as a push.
+func (o Op) IsPush() bool {
+	return o.Opcode <= OP_16
+}
+
+// Element returns the stack element that a push op pushes
+// Small integer opcodes push their number, e.g. 0x81 for OP_1NEGATE. ok is
+// false for ops that are not pushes, including OP_RESERVED.
+func (o Op) Element() ([]byte, bool) {
+	switch {
+	case o.Opcode <= OP_PUSHDATA4:
+		return o.Data, true
+	case o.Opcode == OP_1NEGATE:
+		return []byte{0x81}, true
+	case o.Opcode >= OP_1 && o.Opcode <= OP_16:
+		return []byte{o.Opcode - OP_1 + 1}, true
+	}
+	return nil, false
+}
+
+// IsMinimalPush returns true if the op pushes its data with the shortest
+// encoding, like Core's CheckMinimalPush. Other ops are always minimal.
+func (o Op) IsMinimalPush() bool {
+	size := len(o.Data)
+	switch {
+	case o.Opcode > OP_PUSHDATA4:
+		return true
+	case size == 0:
+		return o.Opcode == OP_0
+	case size == 1 && o.Data[0] >= 1 && o.Data[0] <= 16:
+		return false
+	case size == 1 && o.Data[0] == 0x81:
+		return false
+	case size <= 75:
+		return int(o.Opcode) == size
+	case size <= 255:
+		return o.Opcode == OP_PUSHDATA1
+	case size <= 65535:
+		return o.Opcode == OP_PUSHDATA2
+	}
+	return true
+}
+
+// Tokenizer steps through the ops of a script, like Core's GetOp
+//
+//	t := NewTokenizer(script)
+//	for t.Next() {
+//		op := t.Op()
+//	}
+//	if t.Err() != nil { ... }
+type Tokenizer struct {
+	script []byte
+	offset int
+	op     Op
+	err    error
+}
+
+// NewTokenizer returns a Tokenizer positioned before the first op of script
+func NewTokenizer(script []byte) *Tokenizer {
+	return &Tokenizer{script: script}
+}
+
+// Next parses the next op. It returns false at the end of the script, or
+// if the op is malformed, in which case Err is set.
+func (t *Tokenizer) Next() bool {
+	if t.err != nil || t.offset >= len(t.script) {
+		return false
+	}
+
+	opcode := t.script[t.offset]
+	pc := t.offset + 1
+	if opcode > OP_PUSHDATA4 {
+		t.op = Op{Opcode: opcode}
+		t.offset = pc
+		return true
+	}
+
+	size := int(opcode)
+	var width int
+	switch opcode {
+	case OP_PUSHDATA1:
+		width = 1
+	case OP_PUSHDATA2:
+		width = 2
+	case OP_PUSHDATA4:
+		width = 4
+	}
+	if len(t.script)-pc < width {
+		t.err = ErrTruncatedPush
+		return false
+	}
+	switch width {
+	case 1:
+		size = int(t.script[pc])
+	case 2:
+		size = int(binary.LittleEndian.Uint16(t.script[pc:]))
+	case 4:
+		size = int(binary.LittleEndian.Uint32(t.script[pc:]))
+	}
+	pc += width
+	if size < 0 || len(t.script)-pc < size {
+		t.err = ErrTruncatedPush
+		return false
+	}
+
+	t.op = Op{Opcode: opcode, Data: t.script[pc : pc+size]}
+	t.offset = pc + size
+	return true
+}
+
+// Op returns the op parsed by the last call to Next
+func (t *Tokenizer) Op() Op {
+	return t.op
+}
+
+// Offset returns the index of the byte after the last parsed op
+func (t *Tokenizer) Offset() int {
+	return t.offset
+}
+
+// Err returns the error that stopped the tokenizer, if any
+func (t *Tokenizer) Err() error {
+	return t.err
+}
+
+// Parse splits a script into ops
+// On error, it returns the ops parsed before the malformed one.
+func Parse(script []byte) ([]Op, error) {
+	ops := []Op{}
+	t := NewTokenizer(script)
+	for t.Next() {
+		ops = append(ops, t.Op())
+	}
+	return ops, t.Err()
+}
+
+// IsPushOnly returns true if a script is well-formed and only pushes data
+func IsPushOnly(script []byte) bool {
+	t := NewTokenizer(script)
+	for t.Next() {
+		if !t.Op().IsPush() {
+			return false
+		}
+	}
+	return t.Err() == nil
+}
+
+// PushedData returns the data of every push in a script
+// Small integer opcodes push no data bytes and are skipped.
+func PushedData(script []byte) ([][]byte, error) {
+	data := [][]byte{}
+	t := NewTokenizer(script)
+	for t.Next() {
+		if t.Op().Opcode <= OP_PUSHDATA4 {
+			data = append(data, t.Op().Data)
+		}
+	}
+	return data, t.Err()
+}
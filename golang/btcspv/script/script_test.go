@@ -0,0 +1,152 @@
+package script_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/summa-tx/bitcoin-spv/golang/btcspv/script"
+)
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	long := strings.Repeat("ab", 300)
+	ops, err := script.Parse(decodeHex("0051" + "4c03010203" + "4d2c01" + long + "4e01000000ff" + "76"))
+	assert.Nil(t, err)
+	assert.Equal(t, []script.Op{
+		{Opcode: script.OP_0, Data: []byte{}},
+		{Opcode: script.OP_1},
+		{Opcode: script.OP_PUSHDATA1, Data: []byte{1, 2, 3}},
+		{Opcode: script.OP_PUSHDATA2, Data: decodeHex(long)},
+		{Opcode: script.OP_PUSHDATA4, Data: []byte{0xff}},
+		{Opcode: script.OP_DUP},
+	}, ops)
+
+	for _, s := range []string{"01", "4c", "4c02ff", "4d01", "4d0100", "4e010000", "4e01000000"} {
+		ops, err = script.Parse(decodeHex("76" + s))
+		assert.Equal(t, script.ErrTruncatedPush, err, s)
+		assert.Equal(t, []script.Op{{Opcode: script.OP_DUP}}, ops, s)
+	}
+}
+
+func TestTokenizerOffset(t *testing.T) {
+	tok := script.NewTokenizer(decodeHex("76a914" + strings.Repeat("00", 20) + "88ac"))
+	offsets := []int{}
+	for tok.Next() {
+		offsets = append(offsets, tok.Offset())
+	}
+	assert.Nil(t, tok.Err())
+	assert.Equal(t, []int{1, 2, 23, 24, 25}, offsets)
+}
+
+func TestIsMinimalPush(t *testing.T) {
+	cases := []struct {
+		script  string
+		minimal bool
+	}{
+		{"00", true},
+		{"4c00", false},
+		{"0101", false},
+		{"0110", false},
+		{"0111", true},
+		{"0181", false},
+		{"0100", true},
+		{"4c0111", false},
+		{"4b" + strings.Repeat("00", 75), true},
+		{"4c4b" + strings.Repeat("00", 75), false},
+		{"4c4c" + strings.Repeat("00", 76), true},
+		{"4d4c00" + strings.Repeat("00", 76), false},
+		{"4dff00" + strings.Repeat("00", 255), false},
+		{"4d0001" + strings.Repeat("00", 256), true},
+		{"4e00010000" + strings.Repeat("00", 256), false},
+		{"76", true},
+	}
+
+	for _, c := range cases {
+		ops, err := script.Parse(decodeHex(c.script))
+		assert.Nil(t, err)
+		assert.Equal(t, c.minimal, ops[0].IsMinimalPush(), c.script)
+	}
+}
+
+func TestIsPushOnly(t *testing.T) {
+	assert.True(t, script.IsPushOnly(decodeHex("")))
+	assert.True(t, script.IsPushOnly(decodeHex("004f50510260014c0101")))
+	assert.False(t, script.IsPushOnly(decodeHex("0061")))
+	assert.False(t, script.IsPushOnly(decodeHex("004c")))
+
+	data, err := script.PushedData(decodeHex("0051026001"))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{{}, {0x60, 0x01}}, data)
+}
+
+func TestElement(t *testing.T) {
+	ops, err := script.Parse(decodeHex("00" + "020102" + "4f" + "51" + "60" + "50" + "76"))
+	assert.Nil(t, err)
+
+	expected := [][]byte{{}, {0x01, 0x02}, {0x81}, {0x01}, {0x10}}
+	for i := range expected {
+		element, ok := ops[i].Element()
+		assert.True(t, ok)
+		assert.Equal(t, expected[i], element)
+	}
+	for _, op := range ops[len(expected):] {
+		_, ok := op.Element()
+		assert.False(t, ok)
+	}
+}
+
+func TestOpcodeName(t *testing.T) {
+	assert.Equal(t, "0", script.OpcodeName(script.OP_0))
+	assert.Equal(t, "-1", script.OpcodeName(script.OP_1NEGATE))
+	assert.Equal(t, "16", script.OpcodeName(script.OP_16))
+	assert.Equal(t, "OP_CHECKLOCKTIMEVERIFY", script.OpcodeName(script.OP_NOP2))
+	assert.Equal(t, "OP_CHECKSIGADD", script.OpcodeName(script.OP_CHECKSIGADD))
+	assert.Equal(t, "OP_UNKNOWN", script.OpcodeName(0x14))
+	assert.Equal(t, "OP_UNKNOWN", script.OpcodeName(0xbb))
+}
+
+func TestDisasm(t *testing.T) {
+	// Expected output of Core's decodescript
+	cases := []struct {
+		script string
+		asm    string
+	}{
+		{"", ""},
+		{
+			"76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac",
+			"OP_DUP OP_HASH160 62e907b15cbf27d5425399ebf6f0fb50ebb88f18 OP_EQUALVERIFY OP_CHECKSIG",
+		},
+		{
+			"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			"0 751e76e8199196d454941c45d1b3a323f1433bd6",
+		},
+		{
+			"6a0b68656c6c6f20776f726c64",
+			"OP_RETURN 68656c6c6f20776f726c64",
+		},
+		{"0105", "5"},
+		{"02ff00", "255"},
+		{"0181", "-1"},
+		{"0180", "0"},
+		{"04ffffff7f", "2147483647"},
+		{"04ffffffff", "-2147483647"},
+		{"4c0105", "5"},
+		{"4f5160", "-1 1 16"},
+		{"b1b275bb", "OP_CHECKLOCKTIMEVERIFY OP_CHECKSEQUENCEVERIFY OP_DROP OP_UNKNOWN"},
+		{"4c", "[error]"},
+		{"76030102", "OP_DUP [error]"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.asm, script.Disasm(decodeHex(c.script)), c.script)
+	}
+}
@@ -0,0 +1,25 @@
+// Package sdkuint converts between btcspv.Uint256 and the Cosmos SDK's sdk.Uint
+//
+// btcspv itself does not depend on the Cosmos SDK. Import this package only
+// if your module stores targets or work as sdk.Uint.
+package sdkuint
+
+import (
+	"math/big"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// ToSDK converts a btcspv.Uint256 to an sdk.Uint
+func ToSDK(u btcspv.Uint256) sdk.Uint {
+	return sdk.NewUintFromBigInt(u.Big())
+}
+
+// FromSDK converts an sdk.Uint to a btcspv.Uint256
+// Errors if the number does not fit in 256 bits
+func FromSDK(u sdk.Uint) (btcspv.Uint256, error) {
+	// sdk.Uint does not expose its underlying big.Int
+	i, _ := new(big.Int).SetString(u.String(), 10)
+	return btcspv.Uint256FromBig(i)
+}
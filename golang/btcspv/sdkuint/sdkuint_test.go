@@ -0,0 +1,34 @@
+package sdkuint_test
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+	"github.com/summa-tx/bitcoin-spv/golang/btcspv/sdkuint"
+)
+
+func TestRoundTrip(t *testing.T) {
+	cases := []btcspv.Uint256{
+		{},
+		btcspv.NewUint256(1),
+		btcspv.NewUint256(0xffff).Lsh(208),
+		{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)},
+	}
+
+	for _, u := range cases {
+		converted := sdkuint.ToSDK(u)
+		assert.Equal(t, u.String(), converted.String())
+
+		back, err := sdkuint.FromSDK(converted)
+		assert.Nil(t, err)
+		assert.Equal(t, u, back)
+	}
+}
+
+func TestFromSDK(t *testing.T) {
+	u, err := sdkuint.FromSDK(sdk.NewUint(1234))
+	assert.Nil(t, err)
+	assert.Equal(t, btcspv.NewUint256(1234), u)
+}
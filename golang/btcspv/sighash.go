@@ -0,0 +1,467 @@
+package btcspv
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Sighash flags. SighashDefault is only valid for taproot spends, where it
+// commits to the same data as SighashAll.
+const (
+	SighashDefault      uint32 = 0x00
+	SighashAll          uint32 = 0x01
+	SighashNone         uint32 = 0x02
+	SighashSingle       uint32 = 0x03
+	SighashAnyoneCanPay uint32 = 0x80
+)
+
+// opCodeSeparator is removed from legacy script codes before hashing
+const opCodeSeparator = 0xab
+
+// sighashOne is the digest signed when a legacy SIGHASH_SINGLE input has no
+// matching output. It is the number 1 as a LE uint256.
+var sighashOne = Hash256Digest{1}
+
+// TapscriptSpend identifies the tapleaf for a BIP342 script path spend
+// CodeSeparatorPos is the opcode position of the last executed
+// OP_CODESEPARATOR, or 0xffffffff if none was executed.
+type TapscriptSpend struct {
+	LeafHash         Hash256Digest
+	CodeSeparatorPos uint32
+}
+
+// sighashBase returns the base type of a sighash flag
+func sighashBase(flag uint32) uint32 {
+	return flag & 0x1f
+}
+
+// splitVector returns the items of a vin or vout, in order
+func splitVector(vector []byte, determineLength func([]byte) (uint64, error), extract func([]byte, uint) ([]byte, error)) ([][]byte, error) {
+	count, end, err := readVector(vector, 0, determineLength)
+	if err != nil {
+		return nil, err
+	}
+	if end != uint64(len(vector)) {
+		return nil, NewSPVError(ErrCodeBadLength, "Vector has bytes after its last item")
+	}
+
+	items := make([][]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		item, err := extract(vector, uint(i))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// splitInputs returns the inputs of a vin
+func splitInputs(vin []byte) ([][]byte, error) {
+	inputs, err := splitVector(vin, DetermineInputLength, ExtractInputAtIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, NewSPVError(ErrCodeInvalidVin, "Vin has no inputs")
+	}
+	return inputs, nil
+}
+
+// splitOutputs returns the outputs of a vout
+func splitOutputs(vout []byte) ([][]byte, error) {
+	return splitVector(vout, DetermineOutputLength, ExtractOutputAtIndex)
+}
+
+// checkVersionLocktime checks the lengths of a version and locktime
+func checkVersionLocktime(version, locktime []byte) error {
+	if len(version) != 4 || len(locktime) != 4 {
+		return NewSPVError(ErrCodeBadLength, "Version and locktime must be 4 bytes")
+	}
+	return nil
+}
+
+// inputSequenceLE returns the LE sequence of an input, legacy or witness
+func inputSequenceLE(input []byte) []byte {
+	return input[len(input)-4:]
+}
+
+// getOp advances pc past the opcode at pc, exactly like Core's GetScriptOp
+// On failure pc may be advanced part way through the opcode.
+func getOp(script []byte, pc int) (byte, int, bool) {
+	if pc >= len(script) {
+		return 0, pc, false
+	}
+	opcode := script[pc]
+	pc++
+
+	if opcode > 0x4e {
+		return opcode, pc, true
+	}
+
+	var size int
+	switch {
+	case opcode < 0x4c:
+		size = int(opcode)
+	case opcode == 0x4c:
+		if len(script)-pc < 1 {
+			return opcode, pc, false
+		}
+		size = int(script[pc])
+		pc++
+	case opcode == 0x4d:
+		if len(script)-pc < 2 {
+			return opcode, pc, false
+		}
+		size = int(binary.LittleEndian.Uint16(script[pc:]))
+		pc += 2
+	default:
+		if len(script)-pc < 4 {
+			return opcode, pc, false
+		}
+		size = int(binary.LittleEndian.Uint32(script[pc:]))
+		pc += 4
+	}
+
+	if size < 0 || len(script)-pc < size {
+		return opcode, pc, false
+	}
+	return opcode, pc + size, true
+}
+
+// legacyScriptCode serializes a script code with OP_CODESEPARATORs removed
+// This mirrors Core's CTransactionSignatureSerializer, including its
+// behavior on scripts with a truncated final push.
+func legacyScriptCode(script []byte) []byte {
+	separators := 0
+	for pc, ok := 0, true; ok; {
+		var opcode byte
+		opcode, pc, ok = getOp(script, pc)
+		if ok && opcode == opCodeSeparator {
+			separators++
+		}
+	}
+
+	code := encodeVarInt(uint64(len(script) - separators))
+	begin := 0
+	pc := 0
+	for {
+		opcode, next, ok := getOp(script, pc)
+		pc = next
+		if !ok {
+			break
+		}
+		if opcode == opCodeSeparator {
+			code = append(code, script[begin:pc-1]...)
+			begin = pc
+		}
+	}
+	if begin != len(script) {
+		code = append(code, script[begin:pc]...)
+	}
+	return code
+}
+
+// LegacySighash computes the digest signed by a legacy (pre-segwit) signature
+// prevoutScript is the scriptPubkey or redeem script being satisfied. flag is
+// the full 4-byte hash type. A SIGHASH_SINGLE input without a matching output
+// signs the number 1, as in Core.
+func LegacySighash(version, vin, vout, locktime []byte, index uint, prevoutScript []byte, flag uint32) (Hash256Digest, error) {
+	if err := checkVersionLocktime(version, locktime); err != nil {
+		return Hash256Digest{}, err
+	}
+	inputs, err := splitInputs(vin)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+	outputs, err := splitOutputs(vout)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+	if index >= uint(len(inputs)) {
+		return Hash256Digest{}, newSPVErrorf(ErrCodeOutOfRange, "Input index %d out of range for %d inputs", index, len(inputs))
+	}
+
+	base := sighashBase(flag)
+	anyoneCanPay := flag&SighashAnyoneCanPay != 0
+	if base == SighashSingle && index >= uint(len(outputs)) {
+		return sighashOne, nil
+	}
+
+	preimage := append([]byte{}, version...)
+
+	if anyoneCanPay {
+		preimage = append(preimage, 0x01)
+		preimage = append(preimage, inputs[index][:36]...)
+		preimage = append(preimage, legacyScriptCode(prevoutScript)...)
+		preimage = append(preimage, inputSequenceLE(inputs[index])...)
+	} else {
+		preimage = append(preimage, encodeVarInt(uint64(len(inputs)))...)
+		for i, input := range inputs {
+			preimage = append(preimage, input[:36]...)
+			if uint(i) == index {
+				preimage = append(preimage, legacyScriptCode(prevoutScript)...)
+				preimage = append(preimage, inputSequenceLE(input)...)
+				continue
+			}
+			preimage = append(preimage, 0x00)
+			if base == SighashNone || base == SighashSingle {
+				preimage = append(preimage, 0, 0, 0, 0)
+			} else {
+				preimage = append(preimage, inputSequenceLE(input)...)
+			}
+		}
+	}
+
+	switch base {
+	case SighashNone:
+		preimage = append(preimage, 0x00)
+	case SighashSingle:
+		// Outputs before index are replaced by a value of -1 and an empty script
+		preimage = append(preimage, encodeVarInt(uint64(index+1))...)
+		for i := uint(0); i < index; i++ {
+			preimage = append(preimage, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00)
+		}
+		preimage = append(preimage, outputs[index]...)
+	default:
+		preimage = append(preimage, vout...)
+	}
+
+	preimage = append(preimage, locktime...)
+	preimage = append(preimage, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(preimage[len(preimage)-4:], flag)
+
+	return Hash256(preimage), nil
+}
+
+// HashPrevouts calculates the BIP143 hashPrevouts of a vin
+func HashPrevouts(vin []byte, flag uint32) (Hash256Digest, error) {
+	inputs, err := splitInputs(vin)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+	if flag&SighashAnyoneCanPay != 0 {
+		return Hash256Digest{}, nil
+	}
+
+	preimage := []byte{}
+	for _, input := range inputs {
+		preimage = append(preimage, input[:36]...)
+	}
+	return Hash256(preimage), nil
+}
+
+// HashSequence calculates the BIP143 hashSequence of a vin
+func HashSequence(vin []byte, flag uint32) (Hash256Digest, error) {
+	inputs, err := splitInputs(vin)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+	base := sighashBase(flag)
+	if flag&SighashAnyoneCanPay != 0 || base == SighashSingle || base == SighashNone {
+		return Hash256Digest{}, nil
+	}
+
+	preimage := []byte{}
+	for _, input := range inputs {
+		preimage = append(preimage, inputSequenceLE(input)...)
+	}
+	return Hash256(preimage), nil
+}
+
+// HashOutputs calculates the BIP143 hashOutputs of a vout
+// For SIGHASH_SINGLE, only the output at index is hashed. If there is no
+// output at index, the result is 0.
+func HashOutputs(vout []byte, index uint, flag uint32) (Hash256Digest, error) {
+	outputs, err := splitOutputs(vout)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+
+	switch sighashBase(flag) {
+	case SighashNone:
+		return Hash256Digest{}, nil
+	case SighashSingle:
+		if index >= uint(len(outputs)) {
+			return Hash256Digest{}, nil
+		}
+		return Hash256(outputs[index]), nil
+	default:
+		_, offset, _ := readVarInt(vout, 0)
+		return Hash256(vout[offset:]), nil
+	}
+}
+
+// WitnessSighash computes the digest signed by a BIP143 segwit v0 signature
+// scriptCode is VarInt-prefixed, e.g. from ScriptCodeP2WPKH. value is the
+// value of the output being spent.
+func WitnessSighash(version, vin, vout, locktime []byte, index uint, scriptCode []byte, value uint64, flag uint32) (Hash256Digest, error) {
+	if err := checkVersionLocktime(version, locktime); err != nil {
+		return Hash256Digest{}, err
+	}
+	inputs, err := splitInputs(vin)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+	if index >= uint(len(inputs)) {
+		return Hash256Digest{}, newSPVErrorf(ErrCodeOutOfRange, "Input index %d out of range for %d inputs", index, len(inputs))
+	}
+
+	hashPrevouts, _ := HashPrevouts(vin, flag)
+	hashSequence, _ := HashSequence(vin, flag)
+	hashOutputs, err := HashOutputs(vout, index, flag)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+
+	preimage := append([]byte{}, version...)
+	preimage = append(preimage, hashPrevouts[:]...)
+	preimage = append(preimage, hashSequence[:]...)
+	preimage = append(preimage, inputs[index][:36]...)
+	preimage = append(preimage, scriptCode...)
+	preimage = append(preimage, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(preimage[len(preimage)-8:], value)
+	preimage = append(preimage, inputSequenceLE(inputs[index])...)
+	preimage = append(preimage, hashOutputs[:]...)
+	preimage = append(preimage, locktime...)
+	preimage = append(preimage, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(preimage[len(preimage)-4:], flag)
+
+	return Hash256(preimage), nil
+}
+
+// ScriptCodeP2WPKH returns the BIP143 scriptCode for spending a p2wpkh output
+func ScriptCodeP2WPKH(pkh Hash160Digest) []byte {
+	scriptCode := []byte{0x19, 0x76, 0xa9, 0x14}
+	scriptCode = append(scriptCode, pkh[:]...)
+	return append(scriptCode, 0x88, 0xac)
+}
+
+// TaggedHash implements the BIP340 tagged hash
+func TaggedHash(tag string, msg ...[]byte) Hash256Digest {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, m := range msg {
+		h.Write(m)
+	}
+
+	var digest Hash256Digest
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// TapLeafHash calculates the BIP341 tapleaf hash of a script
+// The leaf version for BIP342 tapscript is 0xc0.
+func TapLeafHash(script []byte, leafVersion byte) Hash256Digest {
+	return TaggedHash("TapLeaf", []byte{leafVersion}, encodeVarInt(uint64(len(script))), script)
+}
+
+// validTaprootFlag returns true if flag is a valid BIP341 hash type
+func validTaprootFlag(flag uint32) bool {
+	switch flag {
+	case 0x00, 0x01, 0x02, 0x03, 0x81, 0x82, 0x83:
+		return true
+	default:
+		return false
+	}
+}
+
+// TaprootSighash computes the digest signed by a BIP341 taproot signature
+// spent holds the output spent by each input, in order. annex is the annex
+// including its 0x50 prefix, or nil. tapscript is nil for key path spends.
+func TaprootSighash(version, vin, vout, locktime []byte, index uint, spent []TxOut, flag uint32, annex []byte, tapscript *TapscriptSpend) (Hash256Digest, error) {
+	if !validTaprootFlag(flag) {
+		return Hash256Digest{}, newSPVErrorf(ErrCodeOutOfRange, "Invalid taproot sighash flag 0x%02x", flag)
+	}
+	if err := checkVersionLocktime(version, locktime); err != nil {
+		return Hash256Digest{}, err
+	}
+	inputs, err := splitInputs(vin)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+	outputs, err := splitOutputs(vout)
+	if err != nil {
+		return Hash256Digest{}, err
+	}
+	if index >= uint(len(inputs)) {
+		return Hash256Digest{}, newSPVErrorf(ErrCodeOutOfRange, "Input index %d out of range for %d inputs", index, len(inputs))
+	}
+	if len(spent) != len(inputs) {
+		return Hash256Digest{}, newSPVErrorf(ErrCodeBadLength, "Expected %d spent outputs, got %d", len(inputs), len(spent))
+	}
+
+	base := sighashBase(flag)
+	anyoneCanPay := flag&SighashAnyoneCanPay != 0
+	if base == SighashSingle && index >= uint(len(outputs)) {
+		return Hash256Digest{}, newSPVErrorf(ErrCodeOutOfRange, "No output at index %d for SIGHASH_SINGLE", index)
+	}
+
+	// Epoch 0, then the hash type
+	msg := []byte{0x00, byte(flag)}
+	msg = append(msg, version...)
+	msg = append(msg, locktime...)
+
+	if !anyoneCanPay {
+		prevouts := sha256.New()
+		amounts := sha256.New()
+		scriptPubkeys := sha256.New()
+		sequences := sha256.New()
+		for i, input := range inputs {
+			prevouts.Write(input[:36])
+			amounts.Write(spent[i].Serialize()[:8])
+			scriptPubkeys.Write(spent[i].Serialize()[8:])
+			sequences.Write(inputSequenceLE(input))
+		}
+		msg = prevouts.Sum(msg)
+		msg = amounts.Sum(msg)
+		msg = scriptPubkeys.Sum(msg)
+		msg = sequences.Sum(msg)
+	}
+
+	if base != SighashNone && base != SighashSingle {
+		_, offset, _ := readVarInt(vout, 0)
+		hashOutputs := sha256.Sum256(vout[offset:])
+		msg = append(msg, hashOutputs[:]...)
+	}
+
+	spendType := byte(0)
+	if tapscript != nil {
+		spendType |= 2
+	}
+	if annex != nil {
+		spendType |= 1
+	}
+	msg = append(msg, spendType)
+
+	if anyoneCanPay {
+		msg = append(msg, inputs[index][:36]...)
+		msg = append(msg, spent[index].Serialize()...)
+		msg = append(msg, inputSequenceLE(inputs[index])...)
+	} else {
+		msg = append(msg, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(msg[len(msg)-4:], uint32(index))
+	}
+
+	if annex != nil {
+		hashAnnex := sha256.Sum256(append(encodeVarInt(uint64(len(annex))), annex...))
+		msg = append(msg, hashAnnex[:]...)
+	}
+
+	if base == SighashSingle {
+		hashOutput := sha256.Sum256(outputs[index])
+		msg = append(msg, hashOutput[:]...)
+	}
+
+	if tapscript != nil {
+		msg = append(msg, tapscript.LeafHash[:]...)
+		// Key version 0
+		msg = append(msg, 0x00)
+		msg = append(msg, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(msg[len(msg)-4:], tapscript.CodeSeparatorPos)
+	}
+
+	return TaggedHash("TapSighash", msg), nil
+}
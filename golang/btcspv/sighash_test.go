@@ -0,0 +1,268 @@
+package btcspv_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// The unsigned P2SH-P2WPKH example from BIP143
+const bip143P2SHP2WPKH = "0100000001db6b1b20aa0fd7b23880be2ecbd4a98130974cf4748fb66092ac4d3ceb1a54770100000000feffffff02b8b4eb0b000000001976a914a457b684d7f0d539a46a45bbc043f35b59d0d96388ac0008af2f000000001976a914fd270b1ee6abcaea97fea7ad0402e8bd8ad6d77c88ac92040000"
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	logIfErr(err)
+	return b
+}
+
+func (suite *UtilsSuite) TestLegacySighash() {
+	byteValue, err := ioutil.ReadFile("testdata/sighash.json")
+	logIfErr(err)
+
+	var vectors [][]interface{}
+	logIfErr(json.Unmarshal(byteValue, &vectors))
+
+	// The first entry describes the format
+	for _, v := range vectors[1:] {
+		tx, err := btcspv.ParseTransaction(decodeHex(v[0].(string)))
+		suite.Nil(err)
+		split := tx.Split()
+
+		script := decodeHex(v[1].(string))
+		index := uint(v[2].(float64))
+		flag := uint32(int32(v[3].(float64)))
+		expected := btcspv.ReverseEndianness(decodeHex(v[4].(string)))
+
+		actual, err := btcspv.LegacySighash(split.Version, split.Vin, split.Vout, split.Locktime, index, script, flag)
+		suite.Nil(err)
+		suite.Equal(expected, actual[:], v[0])
+	}
+}
+
+func (suite *UtilsSuite) TestWitnessSighash() {
+	tx, _ := btcspv.ParseTransaction(decodeHex(bip143P2WPKH))
+	split := tx.Split()
+	flag := btcspv.SighashAll
+
+	hashPrevouts, err := btcspv.HashPrevouts(split.Vin, flag)
+	suite.Nil(err)
+	suite.Equal("96b827c8483d4e9b96712b6713a7b68d6e8003a781feba36c31143470b4efd37", hex.EncodeToString(hashPrevouts[:]))
+
+	hashSequence, err := btcspv.HashSequence(split.Vin, flag)
+	suite.Nil(err)
+	suite.Equal("52b0a642eea2fb7ae638c36f6252b6750293dbe574a806984b8e4d8548339a3b", hex.EncodeToString(hashSequence[:]))
+
+	hashOutputs, err := btcspv.HashOutputs(split.Vout, 1, flag)
+	suite.Nil(err)
+	suite.Equal("863ef3e1a92afbfdb97f31ad0fc7683ee943e9abcf2501590ff8f6551f47e5e5", hex.EncodeToString(hashOutputs[:]))
+
+	pkh, _ := btcspv.NewHash160Digest(decodeHex("1d0f172a0ecb48aee1be1f2687d2963ae33f71a1"))
+	sighash, err := btcspv.WitnessSighash(split.Version, split.Vin, split.Vout, split.Locktime, 1, btcspv.ScriptCodeP2WPKH(pkh), 600000000, flag)
+	suite.Nil(err)
+	suite.Equal("c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670", hex.EncodeToString(sighash[:]))
+
+	tx, _ = btcspv.ParseTransaction(decodeHex(bip143P2SHP2WPKH))
+	split = tx.Split()
+	pkh, _ = btcspv.NewHash160Digest(decodeHex("79091972186c449eb1ded22b78e40d009bdf0089"))
+	sighash, err = btcspv.WitnessSighash(split.Version, split.Vin, split.Vout, split.Locktime, 0, btcspv.ScriptCodeP2WPKH(pkh), 1000000000, flag)
+	suite.Nil(err)
+	suite.Equal("64f3b0f4dd2bb3aa1ce8566d220cc74dda9df97d8490cc81d89d735c92e59fb6", hex.EncodeToString(sighash[:]))
+
+	// Flags that do not commit to a field zero its hash
+	hashPrevouts, _ = btcspv.HashPrevouts(split.Vin, btcspv.SighashAll|btcspv.SighashAnyoneCanPay)
+	suite.Equal(Hash256Digest{}, hashPrevouts)
+	hashSequence, _ = btcspv.HashSequence(split.Vin, btcspv.SighashNone)
+	suite.Equal(Hash256Digest{}, hashSequence)
+	hashOutputs, _ = btcspv.HashOutputs(split.Vout, 2, btcspv.SighashSingle)
+	suite.Equal(Hash256Digest{}, hashOutputs)
+	hashOutputs, _ = btcspv.HashOutputs(split.Vout, 1, btcspv.SighashSingle)
+	output, _ := btcspv.ExtractOutputAtIndex(split.Vout, 1)
+	suite.Equal(btcspv.Hash256(output), hashOutputs)
+
+	_, err = btcspv.WitnessSighash(split.Version, split.Vin, split.Vout, split.Locktime, 1, btcspv.ScriptCodeP2WPKH(pkh), 0, flag)
+	suite.True(errors.Is(err, btcspv.ErrOutOfRange))
+
+	// Versions and locktimes are exactly 4 bytes
+	_, err = btcspv.WitnessSighash(split.Version[:3], split.Vin, split.Vout, split.Locktime, 0, btcspv.ScriptCodeP2WPKH(pkh), 0, flag)
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+	_, err = btcspv.LegacySighash(split.Version, split.Vin, split.Vout, append(split.Locktime, 0), 0, nil, flag)
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+}
+
+func (suite *UtilsSuite) TestTaprootSighash() {
+	tx, _ := btcspv.ParseTransaction(decodeHex(bip143P2WPKH))
+	split := tx.Split()
+	spent := []btcspv.TxOut{
+		{Value: 625000000, ScriptPubkey: decodeHex("2103c9f4836b9a4f77fc0d81f7bcb01b7f1b35916864b9476c241ce9fc198bd25432ac")},
+		{Value: 600000000, ScriptPubkey: decodeHex("51201d0f172a0ecb48aee1be1f2687d2963ae33f71a11d0f172a0ecb48aee1be1f26")},
+	}
+	sighash := func(index uint, flag uint32, annex []byte, tapscript *btcspv.TapscriptSpend) (Hash256Digest, error) {
+		return btcspv.TaprootSighash(split.Version, split.Vin, split.Vout, split.Locktime, index, spent, flag, annex, tapscript)
+	}
+
+	flags := []uint32{0x00, 0x01, 0x02, 0x03, 0x81, 0x82, 0x83}
+	seen := map[Hash256Digest]bool{}
+	for _, flag := range flags {
+		for index := uint(0); index < 2; index++ {
+			digest, err := sighash(index, flag, nil, nil)
+			suite.Nil(err)
+			seen[digest] = true
+
+			annexed, err := sighash(index, flag, []byte{0x50, 0x01}, nil)
+			suite.Nil(err)
+			suite.NotEqual(digest, annexed)
+
+			leaf := &btcspv.TapscriptSpend{btcspv.TapLeafHash([]byte{0x51}, 0xc0), 0xffffffff}
+			scripted, err := sighash(index, flag, nil, leaf)
+			suite.Nil(err)
+			suite.NotEqual(digest, scripted)
+		}
+	}
+	// Every flag and index commits to something different
+	suite.Len(seen, len(flags)*2)
+
+	// ANYONECANPAY does not commit to the other inputs
+	anyone, _ := sighash(1, 0x81, nil, nil)
+	spent[0].Value++
+	changed, _ := sighash(1, 0x81, nil, nil)
+	suite.Equal(anyone, changed)
+	all, _ := sighash(1, 0x01, nil, nil)
+	spent[0].Value--
+	unchanged, _ := sighash(1, 0x01, nil, nil)
+	suite.NotEqual(all, unchanged)
+
+	_, err := sighash(0, 0x04, nil, nil)
+	suite.True(errors.Is(err, btcspv.ErrOutOfRange))
+	_, err = sighash(2, 0x01, nil, nil)
+	suite.True(errors.Is(err, btcspv.ErrOutOfRange))
+	_, err = btcspv.TaprootSighash(split.Version, split.Vin, split.Vout, split.Locktime, 0, spent[:1], 0x01, nil, nil)
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+	_, err = btcspv.TaprootSighash(split.Version, split.Vin, split.Vout, nil, 0, spent, 0x01, nil, nil)
+	suite.EqualError(err, "Version and locktime must be 4 bytes")
+	_, err = btcspv.TaprootSighash(split.Version, append(split.Vin, 0), split.Vout, split.Locktime, 0, spent, 0x01, nil, nil)
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+}
+
+// The keyPathSpending transaction from BIP341's wallet-test-vectors.json
+const bip341KeyPathTx = "02000000097de20cbff686da83a54981d2b9bab3586f4ca7e48f57f5b55963115f3b334e9c010000000000000000d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd990000000000fffffffff8e1f583384333689228c5d28eac13366be082dc57441760d957275419a418420000000000fffffffff0689180aa63b30cb162a73c6d2a38b7eeda2a83ece74310fda0843ad604853b0100000000feffffffaa5202bdf6d8ccd2ee0f0202afbbb7461d9264a25e5bfd3c5a52ee1239e0ba6c0000000000feffffff956149bdc66faa968eb2be2d2faa29718acbfe3941215893a2a3446d32acd050000000000000000000e664b9773b88c09c32cb70a2a3e4da0ced63b7ba3b22f848531bbb1d5d5f4c94010000000000000000e9aa6b8e6c9de67619e6a3924ae25696bb7b694bb677a632a74ef7eadfd4eabf0000000000ffffffffa778eb6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82af10100000000ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac807840cb0000000020ac9a87f5594be208f8532db38cff670c450ed2fea8fcdefcc9a663f78bab962b0065cd1d"
+
+// bip341KeyPathSpent returns the utxosSpent of the keyPathSpending vectors
+func bip341KeyPathSpent() []btcspv.TxOut {
+	scripts := []string{
+		"512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343",
+		"5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3",
+		"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac",
+		"5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e",
+		"512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605",
+		"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc",
+		"512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831",
+		"5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5",
+		"512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220",
+	}
+	values := []uint64{420000000, 462000000, 294000000, 504000000, 630000000, 378000000, 672000000, 546000000, 588000000}
+
+	spent := []btcspv.TxOut{}
+	for i := range scripts {
+		spent = append(spent, btcspv.TxOut{Value: values[i], ScriptPubkey: decodeHex(scripts[i])})
+	}
+	return spent
+}
+
+func (suite *UtilsSuite) TestTaprootSighashBIP341() {
+	tx, err := btcspv.ParseTransaction(decodeHex(bip341KeyPathTx))
+	suite.Nil(err)
+	split := tx.Split()
+	spent := bip341KeyPathSpent()
+
+	// The inputSpending cases, covering every hash type
+	vectors := []struct {
+		index   uint
+		flag    uint32
+		sighash string
+	}{
+		{0, 0x03, "2514a6272f85cfa0f45eb907fcb0d121b808ed37c6ea160a5a9046ed5526d555"},
+		{1, 0x83, "325a644af47e8a5a2591cda0ab0723978537318f10e6a63d4eed783b96a71a4d"},
+		{3, 0x01, "bf013ea93474aa67815b1b6cc441d23b64fa310911d991e713cd34c7f5d46669"},
+		{4, 0x00, "4f900a0bae3f1446fd48490c2958b5a023228f01661cda3496a11da502a7f7ef"},
+		{6, 0x02, "15f25c298eb5cdc7eb1d638dd2d45c97c4c59dcaec6679cfc16ad84f30876b85"},
+		{7, 0x82, "cd292de50313804dabe4685e83f923d2969577191a3e1d2882220dca88cbeb10"},
+		{8, 0x81, "cccb739eca6c13a8a89e6e5cd317ffe55669bbda23f2fd37b0f18755e008edd2"},
+	}
+
+	for _, v := range vectors {
+		actual, err := btcspv.TaprootSighash(split.Version, split.Vin, split.Vout, split.Locktime, v.index, spent, v.flag, nil, nil)
+		suite.Nil(err)
+		suite.Equal(v.sighash, hex.EncodeToString(actual[:]), "input %d", v.index)
+	}
+}
+
+func (suite *UtilsSuite) TestTaprootSighashAnnex() {
+	// The BIP341 vectors have no annex, so build the SigMsg of a
+	// SIGHASH_ALL key path spend with an annex directly from the BIP
+	tx, _ := btcspv.ParseTransaction(decodeHex(bip341KeyPathTx))
+	split := tx.Split()
+	spent := bip341KeyPathSpent()
+	annex := []byte{0x50, 0xde, 0xad}
+
+	single := func(b []byte) []byte {
+		digest := sha256.Sum256(b)
+		return digest[:]
+	}
+	var prevouts, amounts, scriptPubkeys, sequences, outputs []byte
+	for i := range tx.Inputs {
+		prevouts = append(prevouts, tx.Inputs[i].PrevTxID[:]...)
+		prevouts = append(prevouts, byte(tx.Inputs[i].PrevIndex), byte(tx.Inputs[i].PrevIndex>>8), byte(tx.Inputs[i].PrevIndex>>16), byte(tx.Inputs[i].PrevIndex>>24))
+		sequence, _ := btcspv.ExtractSequenceLEWitness(mustInput(split.Vin, uint(i)))
+		sequences = append(sequences, sequence...)
+	}
+	for i := range spent {
+		value := make([]byte, 8)
+		for j := 0; j < 8; j++ {
+			value[j] = byte(spent[i].Value >> (8 * uint(j)))
+		}
+		amounts = append(amounts, value...)
+		scriptPubkeys = append(scriptPubkeys, byte(len(spent[i].ScriptPubkey)))
+		scriptPubkeys = append(scriptPubkeys, spent[i].ScriptPubkey...)
+	}
+	outputs = split.Vout[1:]
+
+	msg := []byte{0x00, 0x01}
+	msg = append(msg, split.Version...)
+	msg = append(msg, split.Locktime...)
+	msg = append(msg, single(prevouts)...)
+	msg = append(msg, single(amounts)...)
+	msg = append(msg, single(scriptPubkeys)...)
+	msg = append(msg, single(sequences)...)
+	msg = append(msg, single(outputs)...)
+	msg = append(msg, 0x01)          // spend_type: key path, with annex
+	msg = append(msg, 0x03, 0, 0, 0) // input_index
+	msg = append(msg, single(append([]byte{byte(len(annex))}, annex...))...)
+	expected := btcspv.TaggedHash("TapSighash", msg)
+
+	actual, err := btcspv.TaprootSighash(split.Version, split.Vin, split.Vout, split.Locktime, 3, spent, 0x01, annex, nil)
+	suite.Nil(err)
+	suite.Equal(expected, actual)
+
+	unannexed, _ := btcspv.TaprootSighash(split.Version, split.Vin, split.Vout, split.Locktime, 3, spent, 0x01, nil, nil)
+	suite.Equal("bf013ea93474aa67815b1b6cc441d23b64fa310911d991e713cd34c7f5d46669", hex.EncodeToString(unannexed[:]))
+}
+
+// mustInput returns the input at index in vin
+func mustInput(vin []byte, index uint) []byte {
+	input, err := btcspv.ExtractInputAtIndex(vin, index)
+	logIfErr(err)
+	return input
+}
+
+func (suite *UtilsSuite) TestTaggedHash() {
+	tag := sha256.Sum256([]byte("TapLeaf"))
+	preimage := append(append(tag[:], tag[:]...), 0xc0, 0x01, 0x51)
+	expected := sha256.Sum256(preimage)
+
+	suite.Equal(Hash256Digest(expected), btcspv.TaggedHash("TapLeaf", []byte{0xc0}, []byte{0x01, 0x51}))
+	suite.Equal(Hash256Digest(expected), btcspv.TapLeafHash([]byte{0x51}, 0xc0))
+}
@@ -0,0 +1,197 @@
+package btcspv
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// ParseDERSignature parses a strict BIP66 DER ECDSA signature
+// The signature must not include a sighash flag byte. R and S must be
+// positive and minimally encoded.
+func ParseDERSignature(sig []byte) (*big.Int, *big.Int, error) {
+	// 0x30 [total-length] 0x02 [R-length] [R] 0x02 [S-length] [S]
+	if len(sig) < 8 || len(sig) > 72 {
+		return nil, nil, newSPVErrorf(ErrCodeInvalidSignature, "DER signature length %d out of range", len(sig))
+	}
+	if sig[0] != 0x30 || int(sig[1]) != len(sig)-2 {
+		return nil, nil, NewSPVError(ErrCodeInvalidSignature, "Malformed DER sequence")
+	}
+
+	lenR := int(sig[3])
+	if sig[2] != 0x02 || lenR == 0 || 5+lenR >= len(sig) {
+		return nil, nil, NewSPVError(ErrCodeInvalidSignature, "Malformed DER R value")
+	}
+	lenS := int(sig[5+lenR])
+	if sig[4+lenR] != 0x02 || lenS == 0 || 6+lenR+lenS != len(sig) {
+		return nil, nil, NewSPVError(ErrCodeInvalidSignature, "Malformed DER S value")
+	}
+
+	r := sig[4 : 4+lenR]
+	s := sig[6+lenR:]
+	for _, n := range [][]byte{r, s} {
+		if n[0]&0x80 != 0 {
+			return nil, nil, NewSPVError(ErrCodeInvalidSignature, "Negative DER integer")
+		}
+		if len(n) > 1 && n[0] == 0 && n[1]&0x80 == 0 {
+			return nil, nil, NewSPVError(ErrCodeInvalidSignature, "DER integer is not minimally encoded")
+		}
+	}
+
+	return new(big.Int).SetBytes(r), new(big.Int).SetBytes(s), nil
+}
+
+// ParsePubkey parses a compressed or uncompressed secp256k1 public key
+// Hybrid keys are rejected, as they are under Core's standardness rules.
+func ParsePubkey(pubkey []byte) (*btcec.PublicKey, error) {
+	if len(pubkey) == 0 || (pubkey[0] != 0x02 && pubkey[0] != 0x03 && pubkey[0] != 0x04) {
+		return nil, NewSPVError(ErrCodeInvalidPubkey, "Pubkey must be compressed or uncompressed")
+	}
+	key, err := btcec.ParsePubKey(pubkey, btcec.S256())
+	if err != nil {
+		return nil, NewSPVError(ErrCodeInvalidPubkey, err.Error())
+	}
+	return key, nil
+}
+
+// CompressPubkey returns the 33-byte compressed form of a public key
+func CompressPubkey(pubkey []byte) ([]byte, error) {
+	key, err := ParsePubkey(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return key.SerializeCompressed(), nil
+}
+
+// PKHFromPubkey returns the hash160 of a public key as given
+// Compressed and uncompressed forms of a key have different PKHs.
+func PKHFromPubkey(pubkey []byte) (Hash160Digest, error) {
+	if _, err := ParsePubkey(pubkey); err != nil {
+		return Hash160Digest{}, err
+	}
+	return Hash160(pubkey), nil
+}
+
+// WPKHFromPubkey returns the hash160 of the compressed form of a public key
+// Witness programs must commit to compressed keys.
+func WPKHFromPubkey(pubkey []byte) (Hash160Digest, error) {
+	compressed, err := CompressPubkey(pubkey)
+	if err != nil {
+		return Hash160Digest{}, err
+	}
+	return Hash160(compressed), nil
+}
+
+// P2WPKHFromPubkey returns the p2wpkh output script of a public key
+// Like the Solidity implementation, uncompressed keys are compressed first.
+func P2WPKHFromPubkey(pubkey []byte) ([]byte, error) {
+	wpkh, err := WPKHFromPubkey(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{0x00, 0x14}, wpkh[:]...), nil
+}
+
+// VerifyECDSA checks a DER ECDSA signature over a digest, e.g. a sighash
+// The signature must not include a sighash flag byte. Like Core, it rejects
+// high-S signatures, which are malleable.
+func VerifyECDSA(pubkey []byte, digest Hash256Digest, sig []byte) (bool, error) {
+	key, err := ParsePubkey(pubkey)
+	if err != nil {
+		return false, err
+	}
+	r, s, err := ParseDERSignature(sig)
+	if err != nil {
+		return false, err
+	}
+
+	halfOrder := new(big.Int).Rsh(btcec.S256().N, 1)
+	if s.Cmp(halfOrder) > 0 {
+		return false, NewSPVError(ErrCodeInvalidSignature, "Signature S value is high")
+	}
+
+	if !ecdsa.Verify(key.ToECDSA(), digest[:], r, s) {
+		return false, NewSPVError(ErrCodeInvalidSignature, "Signature is not valid")
+	}
+	return true, nil
+}
+
+// CheckBitcoinSig checks a signature against a p2wpkh output script
+// This mirrors checkBitcoinSig in the Solidity CheckBitcoinSigs library.
+func CheckBitcoinSig(p2wpkhOutputScript []byte, pubkey []byte, digest Hash256Digest, sig []byte) (bool, error) {
+	expected, err := P2WPKHFromPubkey(pubkey)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(expected, p2wpkhOutputScript) {
+		return false, NewSPVError(ErrCodeInvalidPubkey, "Pubkey does not match the p2wpkh output script")
+	}
+	return VerifyECDSA(pubkey, digest, sig)
+}
+
+// liftX returns the point with x coordinate x and an even y coordinate
+func liftX(x *big.Int) (*big.Int, *big.Int, bool) {
+	curve := btcec.S256()
+	p := curve.P
+	if x.Cmp(p) >= 0 {
+		return nil, nil, false
+	}
+
+	// y^2 = x^3 + 7. As p = 3 mod 4, a root is c^((p+1)/4)
+	c := new(big.Int).Exp(x, big.NewInt(3), p)
+	c.Add(c, big.NewInt(7)).Mod(c, p)
+	exp := new(big.Int).Add(p, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y := new(big.Int).Exp(c, exp, p)
+
+	if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(c) != 0 {
+		return nil, nil, false
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(p, y)
+	}
+	return x, y, true
+}
+
+// VerifySchnorr checks a BIP340 Schnorr signature over a 32-byte message
+// pubkey is the 32-byte x-only public key, as in a taproot output.
+func VerifySchnorr(pubkey []byte, msg Hash256Digest, sig []byte) (bool, error) {
+	if len(pubkey) != 32 {
+		return false, newSPVErrorf(ErrCodeInvalidPubkey, "Expected a 32-byte x-only pubkey, got %d bytes", len(pubkey))
+	}
+	if len(sig) != 64 {
+		return false, newSPVErrorf(ErrCodeInvalidSignature, "Expected a 64-byte Schnorr signature, got %d bytes", len(sig))
+	}
+
+	curve := btcec.S256()
+	px, py, ok := liftX(new(big.Int).SetBytes(pubkey))
+	if !ok {
+		return false, NewSPVError(ErrCodeInvalidPubkey, "Pubkey is not on the curve")
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return false, NewSPVError(ErrCodeInvalidSignature, "Signature value out of range")
+	}
+
+	challenge := TaggedHash("BIP0340/challenge", sig[:32], pubkey, msg[:])
+	e := new(big.Int).SetBytes(challenge[:])
+	e.Mod(e, curve.N)
+
+	// R = s*G - e*P
+	sx, sy := curve.ScalarBaseMult(s.Bytes())
+	ex, ey := curve.ScalarMult(px, py, e.Bytes())
+	ey.Sub(curve.P, ey).Mod(ey, curve.P)
+	rx, ry := curve.Add(sx, sy, ex, ey)
+
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return false, NewSPVError(ErrCodeInvalidSignature, "Signature is not valid")
+	}
+	if ry.Bit(0) == 1 || rx.Cmp(r) != 0 {
+		return false, NewSPVError(ErrCodeInvalidSignature, "Signature is not valid")
+	}
+	return true, nil
+}
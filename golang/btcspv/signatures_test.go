@@ -0,0 +1,188 @@
+package btcspv_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+func (suite *UtilsSuite) TestVerifyECDSA() {
+	// Input 1 of the BIP143 native P2WPKH example
+	tx, _ := btcspv.ParseTransaction(decodeHex(bip143P2WPKH))
+	witness := tx.Inputs[1].Witness
+	sig := witness[0][:len(witness[0])-1]
+	pubkey := []byte(witness[1])
+	sighash, _ := btcspv.NewHash256Digest(decodeHex("c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670"))
+
+	valid, err := btcspv.VerifyECDSA(pubkey, sighash, sig)
+	suite.Nil(err)
+	suite.True(valid)
+
+	wpkh, err := btcspv.WPKHFromPubkey(pubkey)
+	suite.Nil(err)
+	suite.Equal("1d0f172a0ecb48aee1be1f2687d2963ae33f71a1", hex.EncodeToString(wpkh[:]))
+
+	script, err := btcspv.P2WPKHFromPubkey(pubkey)
+	suite.Nil(err)
+	valid, err = btcspv.CheckBitcoinSig(script, pubkey, sighash, sig)
+	suite.Nil(err)
+	suite.True(valid)
+
+	// Another pubkey's script
+	_, err = btcspv.CheckBitcoinSig(append([]byte{0x00, 0x14}, make([]byte, 20)...), pubkey, sighash, sig)
+	suite.True(errors.Is(err, btcspv.ErrInvalidPubkey))
+
+	// Another digest
+	wrong := sighash
+	wrong[0] ^= 1
+	valid, err = btcspv.VerifyECDSA(pubkey, wrong, sig)
+	suite.False(valid)
+	suite.True(errors.Is(err, btcspv.ErrInvalidSignature))
+
+	// The high-S form of a valid signature is rejected
+	r, s, err := btcspv.ParseDERSignature(sig)
+	suite.Nil(err)
+	highS := new(big.Int).Sub(btcec.S256().N, s)
+	rBytes, sBytes := derInt(r), derInt(highS)
+	malleated := []byte{0x30, byte(4 + len(rBytes) + len(sBytes)), 0x02, byte(len(rBytes))}
+	malleated = append(malleated, rBytes...)
+	malleated = append(malleated, 0x02, byte(len(sBytes)))
+	malleated = append(malleated, sBytes...)
+	valid, err = btcspv.VerifyECDSA(pubkey, sighash, malleated)
+	suite.False(valid)
+	suite.True(errors.Is(err, btcspv.ErrInvalidSignature))
+
+	// The uncompressed form of the key verifies, and commits to the same wpkh
+	key, _ := btcspv.ParsePubkey(pubkey)
+	uncompressed := key.SerializeUncompressed()
+	valid, err = btcspv.VerifyECDSA(uncompressed, sighash, sig)
+	suite.Nil(err)
+	suite.True(valid)
+	uncompressedWPKH, _ := btcspv.WPKHFromPubkey(uncompressed)
+	suite.Equal(wpkh, uncompressedWPKH)
+	pkh, _ := btcspv.PKHFromPubkey(uncompressed)
+	suite.NotEqual(wpkh, pkh)
+
+	_, err = btcspv.VerifyECDSA(append([]byte{0x06}, uncompressed[1:]...), sighash, sig)
+	suite.True(errors.Is(err, btcspv.ErrInvalidPubkey))
+	_, err = btcspv.VerifyECDSA(pubkey[:32], sighash, sig)
+	suite.True(errors.Is(err, btcspv.ErrInvalidPubkey))
+}
+
+// derInt encodes a positive integer as a minimal DER integer body
+func derInt(n *big.Int) []byte {
+	b := n.Bytes()
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0x00}, b...)
+	}
+	return b
+}
+
+func (suite *UtilsSuite) TestParseDERSignature() {
+	valid := decodeHex("3006020101020101")
+	r, s, err := btcspv.ParseDERSignature(valid)
+	suite.Nil(err)
+	suite.Equal(int64(1), r.Int64())
+	suite.Equal(int64(1), s.Int64())
+
+	cases := []string{
+		"",
+		"3006020101020101" + "01", // a sighash flag byte
+		"3106020101020101",        // not a sequence
+		"3007020101020101",        // wrong total length
+		"3006030101020101",        // R not an integer
+		"3006020201020101",        // R overruns
+		"3006020101020201",        // S overruns
+		"30050201010200",          // empty S
+		"3006020181020101",        // negative R
+		"3006020101020181",        // negative S
+		"300702020001020101",      // R with a redundant zero byte
+		"30050200020101",          // empty R
+	}
+	for _, c := range cases {
+		_, _, err := btcspv.ParseDERSignature(decodeHex(c))
+		suite.True(errors.Is(err, btcspv.ErrInvalidSignature), c)
+	}
+}
+
+func (suite *UtilsSuite) TestVerifySchnorr() {
+	// BIP340 test vector 0
+	pubkey := decodeHex("f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9")
+	msg := Hash256Digest{}
+	sig := decodeHex("e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0")
+
+	valid, err := btcspv.VerifySchnorr(pubkey, msg, sig)
+	suite.Nil(err)
+	suite.True(valid)
+
+	for i := range sig {
+		tweaked := append([]byte{}, sig...)
+		tweaked[i] ^= 1
+		valid, err = btcspv.VerifySchnorr(pubkey, msg, tweaked)
+		suite.False(valid)
+		suite.True(errors.Is(err, btcspv.ErrInvalidSignature))
+	}
+
+	wrong := msg
+	wrong[31] = 1
+	valid, _ = btcspv.VerifySchnorr(pubkey, wrong, sig)
+	suite.False(valid)
+
+	// x = 5 is not on the curve
+	offCurve := make([]byte, 32)
+	offCurve[31] = 5
+	_, err = btcspv.VerifySchnorr(offCurve, msg, sig)
+	suite.True(errors.Is(err, btcspv.ErrInvalidPubkey))
+	_, err = btcspv.VerifySchnorr(pubkey[1:], msg, sig)
+	suite.True(errors.Is(err, btcspv.ErrInvalidPubkey))
+	_, err = btcspv.VerifySchnorr(pubkey, msg, sig[1:])
+	suite.True(errors.Is(err, btcspv.ErrInvalidSignature))
+}
+
+func (suite *UtilsSuite) TestVerifySchnorrVectors() {
+	// The 32-byte message cases of BIP340's test-vectors.csv
+	vectors := []struct {
+		pubkey  string
+		msg     string
+		sig     string
+		valid   bool
+		comment string
+	}{
+		{"F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9", "0000000000000000000000000000000000000000000000000000000000000000", "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0", true, ""},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A", true, ""},
+		{"DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8", "7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C", "5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7", true, ""},
+		{"25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", "7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3", true, "test fails if msg is reduced modulo p or n"},
+		{"D69C3509BB99E412E68B0FE8544E72837DFA30746D8BE2AA65975F29D22DC7B9", "4DF3C3F68FCC83B27E9D42C90431A72499F17875C81A599B566C9889B9696703", "00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6376AFB1548AF603B3EB45C9F8207DEE1060CB71C04E80F593060B07D28308D7F4", true, ""},
+		{"EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", false, "public key not on the curve"},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "FFF97BD5755EEEA420453A14355235D382F6472F8568A18B2F057A14602975563CC27944640AC607CD107AE10923D9EF7A73C643E166BE5EBEAFA34B1AC553E2", false, "has_even_y(R) is false"},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "1FA62E331EDBC21C394792D2AB1100A7B432B013DF3F6FF4F99FCB33E0E1515F28890B3EDB6E7189B630448B515CE4F8622A954CFE545735AAEA5134FCCDB2BD", false, "negated message"},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769961764B3AA9B2FFCB6EF947B6887A226E8D7C93E00C5ED0C1834FF0D0C2E6DA6", false, "negated s value"},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "0000000000000000000000000000000000000000000000000000000000000000123DDA8328AF9C23A94C1FEECFD123BA4FB73476F0D594DCB65C6425BD186051", false, "sG - eP is infinite"},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "00000000000000000000000000000000000000000000000000000000000000017615FBAF5AE28864013C099742DEADB4DBA87F11AC6754F93780D5A1837CF197", false, "sG - eP is infinite"},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "4A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", false, "sig[0:32] is not an X coordinate on the curve"},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", false, "sig[0:32] is equal to field size"},
+		{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", false, "sig[32:64] is equal to curve order"},
+		{"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC30", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", false, "public key is not a valid X coordinate because it exceeds the field size"},
+	}
+
+	for i, v := range vectors {
+		var msg Hash256Digest
+		copy(msg[:], decodeHex(v.msg))
+		valid, err := btcspv.VerifySchnorr(decodeHex(v.pubkey), msg, decodeHex(v.sig))
+		suite.Equal(v.valid, valid, "vector %d: %s", i, v.comment)
+		if v.valid {
+			suite.Nil(err, "vector %d", i)
+		} else {
+			suite.NotNil(err, "vector %d: %s", i, v.comment)
+		}
+	}
+
+	// Invalid pubkeys are told apart from invalid signatures
+	_, err := btcspv.VerifySchnorr(decodeHex(vectors[5].pubkey), Hash256Digest{}, decodeHex(vectors[5].sig))
+	suite.True(errors.Is(err, btcspv.ErrInvalidPubkey))
+	_, err = btcspv.VerifySchnorr(decodeHex(vectors[14].pubkey), Hash256Digest{}, decodeHex(vectors[14].sig))
+	suite.True(errors.Is(err, btcspv.ErrInvalidPubkey))
+}
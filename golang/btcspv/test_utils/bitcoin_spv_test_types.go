@@ -1,9 +1,6 @@
 package testutils
 
-import (
-	sdk "github.com/cosmos/cosmos-sdk/types"
-	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
-)
+import btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
 
 // Hash256Digest 32-byte double-sha2 digest
 type Hash256Digest = btcspv.Hash256Digest
@@ -23,6 +20,9 @@ type BitcoinHeader = btcspv.BitcoinHeader
 // SPVProof is the base struct for an SPV proof
 type SPVProof = btcspv.SPVProof
 
+// Uint256 is a fixed-width 256-bit unsigned integer
+type Uint256 = btcspv.Uint256
+
 type ExtractSequenceWitnessTC struct {
 	Input  HexBytes `json:"input"`
 	Output uint32   `json:"output"`
@@ -238,6 +238,6 @@ type RetargetAlgorithmTC struct {
 }
 
 type CalculateDifficultyTC struct {
-	Input  sdk.Uint `json:"input"`
-	Output sdk.Int  `json:"output"`
+	Input  Uint256 `json:"input"`
+	Output Uint256 `json:"output"`
 }
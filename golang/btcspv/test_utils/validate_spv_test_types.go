@@ -1,7 +1,5 @@
 package testutils
 
-import sdk "github.com/cosmos/cosmos-sdk/types"
-
 type ProveInput struct {
 	TxIdLE       Hash256Digest `json:"txIdLE"`
 	MerkleRootLE Hash256Digest `json:"merkleRootLE"`
@@ -21,7 +19,7 @@ type CalculateTxIDTC struct {
 
 type ValidateHeaderWorkInput struct {
 	Digest Hash256Digest `json:"digest"`
-	Target sdk.Uint      `json:"target"`
+	Target Uint256       `json:"target"`
 }
 
 type ValidateHeaderWorkTC struct {
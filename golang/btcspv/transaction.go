@@ -0,0 +1,297 @@
+package btcspv
+
+import "encoding/binary"
+
+// TxIn is a parsed transaction input
+type TxIn struct {
+	PrevTxID  Hash256Digest `json:"prev_tx_id"`
+	PrevIndex uint32        `json:"prev_index"`
+	ScriptSig HexBytes      `json:"script_sig"`
+	Sequence  uint32        `json:"sequence"`
+	Witness   []HexBytes    `json:"witness"`
+}
+
+// TxOut is a parsed transaction output
+type TxOut struct {
+	Value        uint64   `json:"value"`
+	ScriptPubkey HexBytes `json:"script_pubkey"`
+}
+
+// Transaction is a parsed legacy or BIP144 segwit transaction
+// PrevTxID, TxID and WTxID are LE. ScriptSig and ScriptPubkey do not include
+// their length prefixes.
+type Transaction struct {
+	Version  uint32        `json:"version"`
+	Inputs   []TxIn        `json:"inputs"`
+	Outputs  []TxOut       `json:"outputs"`
+	Locktime uint32        `json:"locktime"`
+	TxID     Hash256Digest `json:"tx_id"`
+	WTxID    Hash256Digest `json:"wtx_id"`
+}
+
+// encodeVarInt serializes a number as a canonical VarInt
+func encodeVarInt(n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return []byte{byte(n)}
+	case n <= 0xffff:
+		b := []byte{0xfd, 0, 0}
+		binary.LittleEndian.PutUint16(b[1:], uint16(n))
+		return b
+	case n <= 0xffffffff:
+		b := []byte{0xfe, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(b[1:], uint32(n))
+		return b
+	default:
+		b := []byte{0xff, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint64(b[1:], n)
+		return b
+	}
+}
+
+// parseInputs parses a vin into TxIns without witnesses
+func parseInputs(vin []byte) ([]TxIn, error) {
+	count, offset, err := readVarInt(vin, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// An input is at least 41 bytes. Check before sizing the slice by count.
+	if count > (uint64(len(vin))-offset)/41 {
+		return nil, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
+
+	inputs := make([]TxIn, 0, count)
+	for i := uint64(0); i < count; i++ {
+		outpoint, err := safeSlice(vin, offset, offset+36)
+		if err != nil {
+			return nil, err
+		}
+
+		var scriptLength uint64
+		scriptLength, offset, err = readVarInt(vin, offset+36)
+		if err != nil {
+			return nil, err
+		}
+		if scriptLength > uint64(len(vin))-offset {
+			return nil, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+		}
+		scriptSig := vin[offset : offset+scriptLength : offset+scriptLength]
+		offset += scriptLength
+
+		sequence, err := safeSlice(vin, offset, offset+4)
+		if err != nil {
+			return nil, err
+		}
+		offset += 4
+
+		prevTxID, _ := NewHash256Digest(outpoint[:32])
+		inputs = append(inputs, TxIn{
+			PrevTxID:  prevTxID,
+			PrevIndex: binary.LittleEndian.Uint32(outpoint[32:36]),
+			ScriptSig: scriptSig,
+			Sequence:  binary.LittleEndian.Uint32(sequence),
+		})
+	}
+
+	if offset != uint64(len(vin)) {
+		return nil, NewSPVError(ErrCodeInvalidVin, "Vin has bytes after its last input")
+	}
+	return inputs, nil
+}
+
+// parseOutputs parses a vout into TxOuts
+func parseOutputs(vout []byte) ([]TxOut, error) {
+	count, offset, err := readVarInt(vout, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// An output is at least 9 bytes. Check before sizing the slice by count.
+	if count > (uint64(len(vout))-offset)/9 {
+		return nil, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+	}
+
+	outputs := make([]TxOut, 0, count)
+	for i := uint64(0); i < count; i++ {
+		value, err := safeSlice(vout, offset, offset+8)
+		if err != nil {
+			return nil, err
+		}
+
+		var scriptLength uint64
+		scriptLength, offset, err = readVarInt(vout, offset+8)
+		if err != nil {
+			return nil, err
+		}
+		if scriptLength > uint64(len(vout))-offset {
+			return nil, NewSPVError(ErrCodeReadOverrun, "Read overrun")
+		}
+		end := offset + scriptLength
+		outputs = append(outputs, TxOut{
+			Value:        binary.LittleEndian.Uint64(value),
+			ScriptPubkey: vout[offset:end:end],
+		})
+		offset = end
+	}
+
+	if offset != uint64(len(vout)) {
+		return nil, NewSPVError(ErrCodeInvalidVout, "Vout has bytes after its last output")
+	}
+	return outputs, nil
+}
+
+// ParseTransaction parses a raw legacy or BIP144 segwit transaction
+// VarInts must be canonical, so Serialize reproduces the input exactly.
+func ParseTransaction(raw []byte) (Transaction, error) {
+	split, length, err := splitTransaction(raw)
+	if err != nil {
+		return Transaction{}, err
+	}
+	if length != uint64(len(raw)) {
+		return Transaction{}, newSPVErrorf(ErrCodeBadLength, "Transaction has %d bytes after its locktime", uint64(len(raw))-length)
+	}
+	return NewTransaction(split)
+}
+
+// NewTransaction parses a transaction that has been split into its fields,
+// e.g. by ParseBlock
+func NewTransaction(split BlockTx) (Transaction, error) {
+	if len(split.Version) != 4 || len(split.Locktime) != 4 {
+		return Transaction{}, NewSPVError(ErrCodeBadLength, "Version and locktime must be 4 bytes")
+	}
+
+	inputs, err := parseInputs(split.Vin)
+	if err != nil {
+		return Transaction{}, err
+	}
+	outputs, err := parseOutputs(split.Vout)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	if len(split.Witnesses) != 0 {
+		if len(split.Witnesses) != len(inputs) {
+			return Transaction{}, NewSPVError(ErrCodeMalformedTransaction, "Witness count does not match input count")
+		}
+		for i := range inputs {
+			inputs[i].Witness = split.Witnesses[i]
+		}
+	}
+
+	tx := Transaction{
+		Version:  binary.LittleEndian.Uint32(split.Version),
+		Inputs:   inputs,
+		Outputs:  outputs,
+		Locktime: binary.LittleEndian.Uint32(split.Locktime),
+	}
+	tx.TxID = Hash256(tx.SerializeNoWitness())
+	tx.WTxID = Hash256(tx.Serialize())
+
+	return tx, nil
+}
+
+// HasWitness returns true if any input has a witness
+func (t Transaction) HasWitness() bool {
+	for i := range t.Inputs {
+		if len(t.Inputs[i].Witness) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionLE returns the 4-byte LE version
+func (t Transaction) VersionLE() []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, t.Version)
+	return b
+}
+
+// Vin returns the VarInt-prefixed input vector, without witnesses
+func (t Transaction) Vin() []byte {
+	vin := encodeVarInt(uint64(len(t.Inputs)))
+	for _, in := range t.Inputs {
+		vin = append(vin, in.PrevTxID[:]...)
+		vin = append(vin, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(vin[len(vin)-4:], in.PrevIndex)
+		vin = append(vin, encodeVarInt(uint64(len(in.ScriptSig)))...)
+		vin = append(vin, in.ScriptSig...)
+		vin = append(vin, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(vin[len(vin)-4:], in.Sequence)
+	}
+	return vin
+}
+
+// Serialize returns the output with its 8-byte LE value and VarInt-prefixed script
+func (o TxOut) Serialize() []byte {
+	out := make([]byte, 8)
+	binary.LittleEndian.PutUint64(out, o.Value)
+	out = append(out, encodeVarInt(uint64(len(o.ScriptPubkey)))...)
+	return append(out, o.ScriptPubkey...)
+}
+
+// Vout returns the VarInt-prefixed output vector
+func (t Transaction) Vout() []byte {
+	vout := encodeVarInt(uint64(len(t.Outputs)))
+	for _, out := range t.Outputs {
+		vout = append(vout, out.Serialize()...)
+	}
+	return vout
+}
+
+// LocktimeLE returns the 4-byte LE locktime
+func (t Transaction) LocktimeLE() []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, t.Locktime)
+	return b
+}
+
+// SerializeNoWitness returns the legacy serialization, which hashes to TxID
+func (t Transaction) SerializeNoWitness() []byte {
+	raw := t.VersionLE()
+	raw = append(raw, t.Vin()...)
+	raw = append(raw, t.Vout()...)
+	return append(raw, t.LocktimeLE()...)
+}
+
+// Serialize returns the BIP144 serialization if any input has a witness, and
+// the legacy serialization otherwise. It hashes to WTxID.
+func (t Transaction) Serialize() []byte {
+	if !t.HasWitness() {
+		return t.SerializeNoWitness()
+	}
+
+	raw := t.VersionLE()
+	raw = append(raw, 0x00, 0x01)
+	raw = append(raw, t.Vin()...)
+	raw = append(raw, t.Vout()...)
+	for _, in := range t.Inputs {
+		raw = append(raw, encodeVarInt(uint64(len(in.Witness)))...)
+		for _, item := range in.Witness {
+			raw = append(raw, encodeVarInt(uint64(len(item)))...)
+			raw = append(raw, item...)
+		}
+	}
+	return append(raw, t.LocktimeLE()...)
+}
+
+// Split returns the transaction split into the fields of an SPVProof
+func (t Transaction) Split() BlockTx {
+	witnesses := [][]HexBytes{}
+	if t.HasWitness() {
+		for _, in := range t.Inputs {
+			stack := append([]HexBytes{}, in.Witness...)
+			witnesses = append(witnesses, stack)
+		}
+	}
+
+	return BlockTx{
+		Version:   t.VersionLE(),
+		Vin:       t.Vin(),
+		Vout:      t.Vout(),
+		Locktime:  t.LocktimeLE(),
+		Witnesses: witnesses,
+		TxID:      t.TxID,
+	}
+}
@@ -0,0 +1,105 @@
+package btcspv_test
+
+import (
+	"encoding/hex"
+	"errors"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// The signed native P2WPKH example from BIP143
+const bip143P2WPKH = "01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000"
+
+func (suite *TypesSuite) TestParseTransactionLegacy() {
+	proof := suite.ValidProofs[0]
+	raw := []byte{}
+	raw = append(raw, proof.Version...)
+	raw = append(raw, proof.Vin...)
+	raw = append(raw, proof.Vout...)
+	raw = append(raw, proof.Locktime...)
+
+	tx, err := btcspv.ParseTransaction(raw)
+	suite.Nil(err)
+	suite.Equal(uint32(1), tx.Version)
+	suite.Equal(uint32(0), tx.Locktime)
+	suite.Equal(proof.TxID, tx.TxID)
+	suite.Equal(tx.TxID, tx.WTxID)
+	suite.False(tx.HasWitness())
+	suite.Equal(raw, tx.Serialize())
+
+	suite.Len(tx.Inputs, 1)
+	suite.Equal(uint32(0xffffffff), tx.Inputs[0].Sequence)
+	suite.Equal(uint32(0), tx.Inputs[0].PrevIndex)
+	suite.Len(tx.Inputs[0].ScriptSig, 0x6a)
+
+	suite.Len(tx.Outputs, 3)
+	suite.Equal(uint64(0x2f3116), tx.Outputs[0].Value)
+	output, _ := btcspv.ExtractOutputAtIndex(proof.Vout, 1)
+	suite.Equal(output, tx.Outputs[1].Serialize())
+
+	split := tx.Split()
+	suite.Equal(proof.Version, split.Version)
+	suite.Equal(proof.Vin, split.Vin)
+	suite.Equal(proof.Vout, split.Vout)
+	suite.Equal(proof.Locktime, split.Locktime)
+	suite.Equal(proof.TxID, split.TxID)
+	suite.Len(split.Witnesses, 0)
+}
+
+func (suite *TypesSuite) TestParseTransactionSegwit() {
+	raw, _ := hex.DecodeString(bip143P2WPKH)
+
+	tx, err := btcspv.ParseTransaction(raw)
+	suite.Nil(err)
+	suite.True(tx.HasWitness())
+	suite.Equal(raw, tx.Serialize())
+	suite.Equal(btcspv.Hash256(raw), tx.WTxID)
+	suite.Equal(btcspv.Hash256(tx.SerializeNoWitness()), tx.TxID)
+	suite.NotEqual(tx.TxID, tx.WTxID)
+	suite.Equal(uint32(0x11), tx.Locktime)
+
+	suite.Len(tx.Inputs, 2)
+	suite.Len(tx.Inputs[0].Witness, 0)
+	suite.Len(tx.Inputs[1].Witness, 2)
+	suite.Len(tx.Inputs[1].Witness[1], 33)
+	suite.Equal(uint32(0xffffffee), tx.Inputs[0].Sequence)
+	suite.Equal(uint32(1), tx.Inputs[1].PrevIndex)
+	suite.Len(tx.Outputs, 2)
+	suite.Equal(uint64(112340000), tx.Outputs[0].Value)
+
+	split := tx.Split()
+	suite.Equal(tx.TxID, btcspv.CalculateTxID(split.Version, split.Vin, split.Vout, split.Locktime))
+	suite.Equal([]HexBytes{}, split.Witnesses[0])
+	suite.True(btcspv.ValidateVin(split.Vin))
+	suite.True(btcspv.ValidateVout(split.Vout))
+
+	fromSplit, err := btcspv.NewTransaction(split)
+	suite.Nil(err)
+	suite.Equal(tx.WTxID, fromSplit.WTxID)
+
+	split.Witnesses = split.Witnesses[1:]
+	_, err = btcspv.NewTransaction(split)
+	suite.True(errors.Is(err, btcspv.ErrMalformedTransaction))
+}
+
+func (suite *TypesSuite) TestParseTransactionError() {
+	raw, _ := hex.DecodeString(bip143P2WPKH)
+
+	_, err := btcspv.ParseTransaction(append(raw, 0x00))
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+
+	// Every truncation fails cleanly
+	for i := 0; i < len(raw); i++ {
+		_, err = btcspv.ParseTransaction(raw[:i])
+		suite.NotNil(err)
+	}
+
+	// The output count of 2 is re-encoded with 3 bytes
+	tx, _ := btcspv.ParseTransaction(raw)
+	voutStart := 6 + len(tx.Vin())
+	nonCanonical := append([]byte{}, raw[:voutStart]...)
+	nonCanonical = append(nonCanonical, 0xfd, 0x02, 0x00)
+	nonCanonical = append(nonCanonical, raw[voutStart+1:]...)
+	_, err = btcspv.ParseTransaction(nonCanonical)
+	suite.True(errors.Is(err, btcspv.ErrBadVarInt))
+}
@@ -38,12 +38,26 @@ type SPVProof struct {
 	IntermediateNodes HexBytes      `json:"intermediate_nodes"`
 }
 
+// ChainAnchor is a trusted header from which a header chain is validated
+// against the difficulty adjustment rules. EpochStart is the timestamp of the
+// first header in the anchor's 2016-block difficulty epoch. Bits is the
+// anchor's own nBits, which differs from the epoch target if the anchor is a
+// min-difficulty header. If Bits is 0, the anchor uses the epoch target.
+type ChainAnchor struct {
+	Digest      Hash256Digest `json:"digest"`
+	Height      uint32        `json:"height"`
+	Timestamp   uint          `json:"timestamp"`
+	EpochStart  uint          `json:"epoch_start"`
+	EpochTarget Uint256       `json:"epoch_target"`
+	Bits        uint32        `json:"bits"`
+}
+
 // NewHash160Digest instantiates a Hash160Digest from a byte slice
 func NewHash160Digest(b []byte) (Hash160Digest, error) {
 	var h Hash160Digest
 	copied := copy(h[:], b)
 	if copied != 20 {
-		return Hash160Digest{}, fmt.Errorf("Expected 20 bytes in a Hash160Digest, got %d", copied)
+		return Hash160Digest{}, newSPVErrorf(ErrCodeBadLength, "Expected 20 bytes in a Hash160Digest, got %d", copied)
 	}
 	return h, nil
 }
@@ -53,7 +67,7 @@ func NewHash256Digest(b []byte) (Hash256Digest, error) {
 	var h Hash256Digest
 	copied := copy(h[:], b)
 	if copied != 32 {
-		return Hash256Digest{}, fmt.Errorf("Expected 32 bytes in a Hash256Digest, got %d", copied)
+		return Hash256Digest{}, newSPVErrorf(ErrCodeBadLength, "Expected 32 bytes in a Hash256Digest, got %d", copied)
 	}
 	return h, nil
 }
@@ -63,7 +77,7 @@ func NewRawHeader(b []byte) (RawHeader, error) {
 	var h RawHeader
 	copied := copy(h[:], b)
 	if copied != 80 {
-		return RawHeader{}, fmt.Errorf("Expected 80 bytes in a RawHeader got %d", copied)
+		return RawHeader{}, newSPVErrorf(ErrCodeBadLength, "Expected 80 bytes in a RawHeader got %d", copied)
 	}
 	return h, nil
 }
@@ -83,6 +97,19 @@ func HeaderFromRaw(raw RawHeader, height uint32) BitcoinHeader {
 	}
 }
 
+// AnchorFromHeader builds a ChainAnchor from a trusted header and the
+// timestamp of the first header in its difficulty epoch
+func AnchorFromHeader(header BitcoinHeader, epochStart uint) ChainAnchor {
+	return ChainAnchor{
+		header.Hash,
+		header.Height,
+		ExtractTimestamp(header.Raw),
+		epochStart,
+		ExtractTarget(header.Raw),
+		ExtractBits(header.Raw),
+	}
+}
+
 // HeaderFromHex buidls a BitcoinHeader from a hex string and height
 func HeaderFromHex(s string, height uint32) (BitcoinHeader, error) {
 	var raw RawHeader
@@ -94,7 +121,7 @@ func HeaderFromHex(s string, height uint32) (BitcoinHeader, error) {
 
 	copied := copy(raw[:], buf)
 	if copied != 80 {
-		return BitcoinHeader{}, fmt.Errorf("Expected 80 bytes in a Hash256 digest, got %d", copied)
+		return BitcoinHeader{}, newSPVErrorf(ErrCodeBadLength, "Expected 80 bytes in a Hash256 digest, got %d", copied)
 	}
 
 	return HeaderFromRaw(raw, height), nil
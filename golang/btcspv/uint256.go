@@ -0,0 +1,279 @@
+package btcspv
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+)
+
+// Uint256 is a fixed-width 256-bit unsigned integer used for targets and work
+// It is stored as 4 little-endian uint64 limbs, so arithmetic never allocates.
+// Add, Sub, Mul and Lsh wrap modulo 2^256, like Bitcoin Core's arith_uint256.
+type Uint256 [4]uint64
+
+// NewUint256 instantiates a Uint256 from a uint64
+func NewUint256(x uint64) Uint256 {
+	return Uint256{x, 0, 0, 0}
+}
+
+// Uint256FromBytes instantiates a Uint256 from a big-endian bytestring of up
+// to 32 bytes
+func Uint256FromBytes(b []byte) (Uint256, error) {
+	if len(b) > 32 {
+		return Uint256{}, newSPVErrorf(ErrCodeBadLength, "Expected at most 32 bytes in a Uint256, got %d", len(b))
+	}
+
+	var buf [32]byte
+	copy(buf[32-len(b):], b)
+
+	return Uint256{
+		binary.BigEndian.Uint64(buf[24:32]),
+		binary.BigEndian.Uint64(buf[16:24]),
+		binary.BigEndian.Uint64(buf[8:16]),
+		binary.BigEndian.Uint64(buf[0:8]),
+	}, nil
+}
+
+// Uint256FromLE interprets a little-endian digest as a Uint256
+// This is how Bitcoin compares header digests to targets
+func Uint256FromLE(h Hash256Digest) Uint256 {
+	return Uint256{
+		binary.LittleEndian.Uint64(h[0:8]),
+		binary.LittleEndian.Uint64(h[8:16]),
+		binary.LittleEndian.Uint64(h[16:24]),
+		binary.LittleEndian.Uint64(h[24:32]),
+	}
+}
+
+// Uint256FromBig instantiates a Uint256 from a big.Int
+// Errors if the number is negative or does not fit in 256 bits
+func Uint256FromBig(b *big.Int) (Uint256, error) {
+	if b.Sign() < 0 {
+		return Uint256{}, NewSPVError(ErrCodeOutOfRange, "Uint256 must not be negative")
+	}
+	if b.BitLen() > 256 {
+		return Uint256{}, newSPVErrorf(ErrCodeOutOfRange, "Uint256 overflow: bit length %d greater than 256", b.BitLen())
+	}
+	return Uint256FromBytes(b.Bytes())
+}
+
+// Bytes returns the number as a 32-byte big-endian array
+func (u Uint256) Bytes() [32]byte {
+	var buf [32]byte
+	binary.BigEndian.PutUint64(buf[0:8], u[3])
+	binary.BigEndian.PutUint64(buf[8:16], u[2])
+	binary.BigEndian.PutUint64(buf[16:24], u[1])
+	binary.BigEndian.PutUint64(buf[24:32], u[0])
+	return buf
+}
+
+// Big returns the number as a newly allocated big.Int
+func (u Uint256) Big() *big.Int {
+	buf := u.Bytes()
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// Uint64 returns the lowest 64 bits of the number
+func (u Uint256) Uint64() uint64 {
+	return u[0]
+}
+
+// IsZero returns true if the number is 0
+func (u Uint256) IsZero() bool {
+	return u[0]|u[1]|u[2]|u[3] == 0
+}
+
+// BitLen returns the number of bits required to represent the number
+func (u Uint256) BitLen() int {
+	for i := 3; i >= 0; i-- {
+		if u[i] != 0 {
+			return i*64 + bits.Len64(u[i])
+		}
+	}
+	return 0
+}
+
+// Cmp returns -1 if u < v, 0 if u == v, and 1 if u > v
+func (u Uint256) Cmp(v Uint256) int {
+	for i := 3; i >= 0; i-- {
+		if u[i] < v[i] {
+			return -1
+		}
+		if u[i] > v[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Equal returns true if u == v
+func (u Uint256) Equal(v Uint256) bool {
+	return u == v
+}
+
+// LT returns true if u < v
+func (u Uint256) LT(v Uint256) bool {
+	return u.Cmp(v) < 0
+}
+
+// GT returns true if u > v
+func (u Uint256) GT(v Uint256) bool {
+	return u.Cmp(v) > 0
+}
+
+// AddOverflow returns u + v, and whether the addition overflowed
+func (u Uint256) AddOverflow(v Uint256) (Uint256, bool) {
+	var res Uint256
+	var carry uint64
+	res[0], carry = bits.Add64(u[0], v[0], 0)
+	res[1], carry = bits.Add64(u[1], v[1], carry)
+	res[2], carry = bits.Add64(u[2], v[2], carry)
+	res[3], carry = bits.Add64(u[3], v[3], carry)
+	return res, carry != 0
+}
+
+// Add returns u + v, modulo 2^256
+func (u Uint256) Add(v Uint256) Uint256 {
+	res, _ := u.AddOverflow(v)
+	return res
+}
+
+// SubUnderflow returns u - v, and whether the subtraction underflowed
+func (u Uint256) SubUnderflow(v Uint256) (Uint256, bool) {
+	var res Uint256
+	var borrow uint64
+	res[0], borrow = bits.Sub64(u[0], v[0], 0)
+	res[1], borrow = bits.Sub64(u[1], v[1], borrow)
+	res[2], borrow = bits.Sub64(u[2], v[2], borrow)
+	res[3], borrow = bits.Sub64(u[3], v[3], borrow)
+	return res, borrow != 0
+}
+
+// Sub returns u - v, modulo 2^256
+func (u Uint256) Sub(v Uint256) Uint256 {
+	res, _ := u.SubUnderflow(v)
+	return res
+}
+
+// MulOverflow returns u * v, and whether the multiplication overflowed
+func (u Uint256) MulOverflow(v Uint256) (Uint256, bool) {
+	var res [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(u[i], v[j])
+			var c uint64
+			lo, c = bits.Add64(lo, res[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			res[i+j] = lo
+			carry = hi
+		}
+		res[i+4] = carry
+	}
+
+	overflow := res[4]|res[5]|res[6]|res[7] != 0
+	return Uint256{res[0], res[1], res[2], res[3]}, overflow
+}
+
+// Mul returns u * v, modulo 2^256
+func (u Uint256) Mul(v Uint256) Uint256 {
+	res, _ := u.MulOverflow(v)
+	return res
+}
+
+// Div returns u / v, rounded down
+// Division by zero returns zero rather than panicking
+func (u Uint256) Div(v Uint256) Uint256 {
+	if v.IsZero() || u.LT(v) {
+		return Uint256{}
+	}
+	if u[1]|u[2]|u[3] == 0 {
+		return NewUint256(u[0] / v[0])
+	}
+
+	// Shift-and-subtract long division, starting from the highest bit
+	// the quotient can have
+	shift := u.BitLen() - v.BitLen()
+	divisor := v.Lsh(uint(shift))
+	remainder := u
+
+	var quotient Uint256
+	for i := shift; i >= 0; i-- {
+		if !remainder.LT(divisor) {
+			remainder = remainder.Sub(divisor)
+			quotient[i/64] |= 1 << (uint(i) % 64)
+		}
+		divisor = divisor.Rsh(1)
+	}
+
+	return quotient
+}
+
+// Lsh returns u << n, modulo 2^256
+func (u Uint256) Lsh(n uint) Uint256 {
+	if n >= 256 {
+		return Uint256{}
+	}
+
+	var res Uint256
+	limbs := n / 64
+	shift := n % 64
+	for i := 3; i >= int(limbs); i-- {
+		res[i] = u[i-int(limbs)] << shift
+		if shift != 0 && i-int(limbs) > 0 {
+			res[i] |= u[i-int(limbs)-1] >> (64 - shift)
+		}
+	}
+	return res
+}
+
+// Rsh returns u >> n
+func (u Uint256) Rsh(n uint) Uint256 {
+	if n >= 256 {
+		return Uint256{}
+	}
+
+	var res Uint256
+	limbs := n / 64
+	shift := n % 64
+	for i := 0; i < 4-int(limbs); i++ {
+		res[i] = u[i+int(limbs)] >> shift
+		if shift != 0 && i+int(limbs) < 3 {
+			res[i] |= u[i+int(limbs)+1] << (64 - shift)
+		}
+	}
+	return res
+}
+
+// String returns the number in decimal
+func (u Uint256) String() string {
+	return u.Big().String()
+}
+
+// MarshalJSON marshalls the number as a decimal string
+func (u Uint256) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + u.String() + "\""), nil
+}
+
+// UnmarshalJSON unmarshalls a decimal or 0x-prepended hex string, or a number
+func (u *Uint256) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return newSPVErrorf(ErrCodeOutOfRange, "Cannot parse %s as a Uint256", s)
+	}
+
+	res, err := Uint256FromBig(n)
+	if err != nil {
+		return err
+	}
+
+	*u = res
+	return nil
+}
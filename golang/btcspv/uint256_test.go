@@ -0,0 +1,128 @@
+package btcspv_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+type Uint256 = btcspv.Uint256
+
+var two256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+func randUint256(r *rand.Rand) Uint256 {
+	var u Uint256
+	// Vary the bit length so that every limb boundary is exercised
+	limbs := r.Intn(5)
+	for i := 0; i < limbs; i++ {
+		u[i] = r.Uint64()
+	}
+	return u
+}
+
+func mod256(i *big.Int) *big.Int {
+	return i.Mod(i, two256)
+}
+
+func (suite *UtilsSuite) TestUint256Arithmetic() {
+	r := rand.New(rand.NewSource(0))
+
+	for i := 0; i < 2000; i++ {
+		a := randUint256(r)
+		b := randUint256(r)
+		n := uint(r.Intn(300))
+		aBig := a.Big()
+		bBig := b.Big()
+
+		suite.Equal(aBig.Cmp(bBig), a.Cmp(b))
+		suite.Equal(aBig.BitLen(), a.BitLen())
+
+		expected := mod256(new(big.Int).Add(aBig, bBig))
+		suite.Equal(expected.String(), a.Add(b).Big().String(), "%s + %s", a, b)
+
+		expected = mod256(new(big.Int).Sub(aBig, bBig))
+		suite.Equal(expected.String(), a.Sub(b).Big().String(), "%s - %s", a, b)
+
+		product := new(big.Int).Mul(aBig, bBig)
+		actual, overflow := a.MulOverflow(b)
+		suite.Equal(product.BitLen() > 256, overflow)
+		suite.Equal(mod256(product).String(), actual.Big().String(), "%s * %s", a, b)
+
+		if !b.IsZero() {
+			expected = new(big.Int).Quo(aBig, bBig)
+			suite.Equal(expected.String(), a.Div(b).Big().String(), "%s / %s", a, b)
+		}
+
+		expected = mod256(new(big.Int).Lsh(aBig, n))
+		suite.Equal(expected.String(), a.Lsh(n).Big().String(), "%s << %d", a, n)
+
+		expected = new(big.Int).Rsh(aBig, n)
+		suite.Equal(expected.String(), a.Rsh(n).Big().String(), "%s >> %d", a, n)
+
+		roundTrip, err := btcspv.Uint256FromBig(aBig)
+		suite.Nil(err)
+		suite.Equal(a, roundTrip)
+	}
+
+	suite.Equal(Uint256{}, btcspv.NewUint256(7).Div(Uint256{}))
+}
+
+func (suite *UtilsSuite) TestUint256FromBig() {
+	_, err := btcspv.Uint256FromBig(big.NewInt(-1))
+	suite.EqualError(err, "Uint256 must not be negative")
+
+	_, err = btcspv.Uint256FromBig(two256)
+	suite.EqualError(err, "Uint256 overflow: bit length 257 greater than 256")
+
+	_, err = btcspv.Uint256FromBytes(make([]byte, 33))
+	suite.EqualError(err, "Expected at most 32 bytes in a Uint256, got 33")
+}
+
+func (suite *UtilsSuite) TestUint256JSON() {
+	var u Uint256
+
+	err := json.Unmarshal([]byte(`"0x0000000000000000002819a10000000000000000000000000000000000000000"`), &u)
+	suite.Nil(err)
+	suite.Equal(btcspv.NewUint256(0x2819a1).Lsh(160), u)
+
+	err = json.Unmarshal([]byte(`"12345"`), &u)
+	suite.Nil(err)
+	suite.Equal(btcspv.NewUint256(12345), u)
+
+	err = json.Unmarshal([]byte(`67890`), &u)
+	suite.Nil(err)
+	suite.Equal(btcspv.NewUint256(67890), u)
+
+	j, err := json.Marshal(u)
+	suite.Nil(err)
+	suite.Equal(`"67890"`, string(j))
+
+	err = json.Unmarshal([]byte(`"0x"`), &u)
+	suite.EqualError(err, "Cannot parse 0x as a Uint256")
+}
+
+func (suite *UtilsSuite) TestExtractTargetOverflow() {
+	var header RawHeader
+	header[72] = 0x01
+	header[75] = 0xff
+
+	suite.NotPanics(func() {
+		suite.Equal(Uint256{}, btcspv.ExtractTarget(header))
+		suite.Equal(Uint256{}, btcspv.ExtractDifficulty(header, &btcspv.MainNetParams))
+	})
+}
+
+func (suite *UtilsSuite) TestValidateHeaderChainAllocs() {
+	fixture := suite.Fixtures.ValidateHeaderChain
+
+	for i := range fixture {
+		headers := fixture[i].Input
+		allocs := testing.AllocsPerRun(10, func() {
+			btcspv.ValidateHeaderChain(headers, &btcspv.MainNetParams)
+		})
+		suite.Equal(float64(0), allocs)
+	}
+}
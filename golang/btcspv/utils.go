@@ -36,18 +36,18 @@ func DecodeIfHex(s string) []byte {
 }
 
 // EncodeP2SH turns a scripthash into an address
-func EncodeP2SH(sh []byte) (string, error) {
+func EncodeP2SH(sh []byte, net *NetParams) (string, error) {
 	if len(sh) != 20 {
 		return "", fmt.Errorf("SH must be 20 bytes, got %d bytes", len(sh))
 	}
 	if bytes.Equal(sh, make([]byte, len(sh))) {
 		return "", errors.New(ZeroBytesError)
 	}
-	return base58.CheckEncode(sh, 5), nil
+	return base58.CheckEncode(sh, net.ScriptHashAddrID), nil
 }
 
 // EncodeP2PKH turns a pubkey hash into an address
-func EncodeP2PKH(pkh []byte) (string, error) {
+func EncodeP2PKH(pkh []byte, net *NetParams) (string, error) {
 	if len(pkh) != 20 {
 		return "", fmt.Errorf("PKH must be 20 bytes, got %d bytes", len(pkh))
 	}
@@ -55,23 +55,40 @@ func EncodeP2PKH(pkh []byte) (string, error) {
 		return "", errors.New(ZeroBytesError)
 
 	}
-	return base58.CheckEncode(pkh, 0), nil
+	return base58.CheckEncode(pkh, net.PubKeyHashAddrID), nil
 }
 
-func encodeSegWit(payload []byte, version int) (string, error) {
+// EncodeSegWit turns a witness program into an address
+// Version 0 programs are encoded with bech32, and versions 1 to 16 with
+// bech32m. Programs must be 2 to 40 bytes, and version 0 programs must be 20
+// or 32 bytes.
+func EncodeSegWit(payload []byte, version int, net *NetParams) (string, error) {
+	if version < 0 || version > 16 {
+		return "", fmt.Errorf("Witness version must be 0 to 16, got %d", version)
+	}
+	if len(payload) < 2 || len(payload) > 40 {
+		return "", fmt.Errorf("Witness program must be 2 to 40 bytes, got %d bytes", len(payload))
+	}
+	if version == 0 && len(payload) != 20 && len(payload) != 32 {
+		return "", fmt.Errorf("Version 0 witness program must be 20 or 32 bytes, got %d bytes", len(payload))
+	}
 	if bytes.Equal(payload, make([]byte, len(payload))) {
 		return "", errors.New(ZeroBytesError)
 	}
 	adj, _ := bech32.ConvertBits(payload, 8, 5, true)
-	combined := []byte{0x00}
+	combined := []byte{byte(version)}
 	combined = append(combined, adj...)
-	res, _ := bech32.Encode("bc", combined)
-	return res, nil
+
+	constant := uint32(bech32mConst)
+	if version == 0 {
+		constant = bech32Const
+	}
+	return bech32Encode(net.Bech32HRP, combined, constant), nil
 }
 
 // EncodeP2WSH turns a scripthash into an address
-func EncodeP2WSH(sh Hash256Digest) (string, error) {
-	addr, err := encodeSegWit(sh[:], 0)
+func EncodeP2WSH(sh Hash256Digest, net *NetParams) (string, error) {
+	addr, err := EncodeSegWit(sh[:], 0, net)
 	if err != nil {
 		return "", err
 	}
@@ -79,11 +96,23 @@ func EncodeP2WSH(sh Hash256Digest) (string, error) {
 }
 
 // EncodeP2WPKH turns a pubkey hash into an address
-func EncodeP2WPKH(pkh []byte) (string, error) {
+func EncodeP2WPKH(pkh []byte, net *NetParams) (string, error) {
 	if len(pkh) != 20 {
 		return "", fmt.Errorf("WPKH must be 20 bytes, got %d bytes", len(pkh))
 	}
-	addr, err := encodeSegWit(pkh, 0)
+	addr, err := EncodeSegWit(pkh, 0, net)
+	if err != nil {
+		return "", err
+	}
+	return addr, nil
+}
+
+// EncodeP2TR turns a 32-byte x-only taproot output key into an address
+func EncodeP2TR(key []byte, net *NetParams) (string, error) {
+	if len(key) != 32 {
+		return "", fmt.Errorf("TR key must be 32 bytes, got %d bytes", len(key))
+	}
+	addr, err := EncodeSegWit(key, 1, net)
 	if err != nil {
 		return "", err
 	}
@@ -37,7 +37,7 @@ func (suite *UtilsSuite) TestEncodeP2SH() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual, err := btcspv.EncodeP2SH(testCase.Input)
+		actual, err := btcspv.EncodeP2SH(testCase.Input, &btcspv.MainNetParams)
 		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
@@ -49,7 +49,7 @@ func (suite *UtilsSuite) TestEncodeP2PKH() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual, err := btcspv.EncodeP2PKH(testCase.Input)
+		actual, err := btcspv.EncodeP2PKH(testCase.Input, &btcspv.MainNetParams)
 		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
@@ -62,7 +62,7 @@ func (suite *UtilsSuite) TestEncodeP2WSH() {
 		testCase := fixture[i]
 
 		expected := testCase.Output
-		actual, err := btcspv.EncodeP2WSH(testCase.Input)
+		actual, err := btcspv.EncodeP2WSH(testCase.Input, &btcspv.MainNetParams)
 		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
@@ -74,7 +74,7 @@ func (suite *UtilsSuite) TestEncodeP2WPKH() {
 	for i := range fixture {
 		testCase := fixture[i]
 		expected := testCase.Output
-		actual, err := btcspv.EncodeP2WPKH(testCase.Input)
+		actual, err := btcspv.EncodeP2WPKH(testCase.Input, &btcspv.MainNetParams)
 		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
@@ -83,34 +83,71 @@ func (suite *UtilsSuite) TestEncodeP2WPKH() {
 func (suite *UtilsSuite) TestEncodeSegwitErrors() {
 	// All 0s
 	input := make([]byte, 20)
-	actual, err := btcspv.EncodeP2PKH(input)
+	actual, err := btcspv.EncodeP2PKH(input, &btcspv.MainNetParams)
 	suite.Equal("", actual)
 	suite.EqualError(err, btcspv.ZeroBytesError)
 
-	actual, err = btcspv.EncodeP2SH(input)
+	actual, err = btcspv.EncodeP2SH(input, &btcspv.MainNetParams)
 	suite.Equal("", actual)
 	suite.EqualError(err, btcspv.ZeroBytesError)
 
-	actual, err = btcspv.EncodeP2WPKH(input)
+	actual, err = btcspv.EncodeP2WPKH(input, &btcspv.MainNetParams)
 	suite.Equal("", actual)
 	suite.EqualError(err, btcspv.ZeroBytesError)
 
 	WSH, _ := btcspv.NewHash256Digest(make([]byte, 32))
-	actual, err = btcspv.EncodeP2WSH(WSH)
+	actual, err = btcspv.EncodeP2WSH(WSH, &btcspv.MainNetParams)
 	suite.Equal("", actual)
 	suite.EqualError(err, btcspv.ZeroBytesError)
 
 	// Wrong Length
 	input = make([]byte, 1)
-	actual, err = btcspv.EncodeP2PKH(input)
+	actual, err = btcspv.EncodeP2PKH(input, &btcspv.MainNetParams)
 	suite.Equal("", actual)
 	suite.EqualError(err, "PKH must be 20 bytes, got 1 bytes")
 
-	actual, err = btcspv.EncodeP2SH(input)
+	actual, err = btcspv.EncodeP2SH(input, &btcspv.MainNetParams)
 	suite.Equal("", actual)
 	suite.EqualError(err, "SH must be 20 bytes, got 1 bytes")
 
-	actual, err = btcspv.EncodeP2WPKH(input)
+	actual, err = btcspv.EncodeP2WPKH(input, &btcspv.MainNetParams)
 	suite.Equal("", actual)
 	suite.EqualError(err, "WPKH must be 20 bytes, got 1 bytes")
 }
+
+func (suite *UtilsSuite) TestEncodeSegWitBech32m() {
+	// BIP350 test vectors
+	cases := []struct {
+		version int
+		program string
+		net     *btcspv.NetParams
+		address string
+	}{
+		{1, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", &btcspv.MainNetParams, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"},
+		{1, "000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433", &btcspv.TestNet3Params, "tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c"},
+		{1, "751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6", &btcspv.MainNetParams, "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y"},
+		{16, "751e", &btcspv.MainNetParams, "bc1sw50qgdz25j"},
+		{2, "751e76e8199196d454941c45d1b3a323", &btcspv.MainNetParams, "bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs"},
+		{0, "751e76e8199196d454941c45d1b3a323f1433bd6", &btcspv.MainNetParams, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+	}
+	for _, c := range cases {
+		actual, err := btcspv.EncodeSegWit(decodeHex(c.program), c.version, c.net)
+		suite.Nil(err)
+		suite.Equal(c.address, actual)
+	}
+
+	actual, err := btcspv.EncodeP2TR(decodeHex(cases[0].program), &btcspv.MainNetParams)
+	suite.Nil(err)
+	suite.Equal(cases[0].address, actual)
+
+	_, err = btcspv.EncodeP2TR(make([]byte, 20), &btcspv.MainNetParams)
+	suite.EqualError(err, "TR key must be 32 bytes, got 20 bytes")
+	_, err = btcspv.EncodeP2TR(make([]byte, 32), &btcspv.MainNetParams)
+	suite.EqualError(err, btcspv.ZeroBytesError)
+	_, err = btcspv.EncodeSegWit(decodeHex("751e"), 17, &btcspv.MainNetParams)
+	suite.EqualError(err, "Witness version must be 0 to 16, got 17")
+	_, err = btcspv.EncodeSegWit(make([]byte, 41), 1, &btcspv.MainNetParams)
+	suite.EqualError(err, "Witness program must be 2 to 40 bytes, got 41 bytes")
+	_, err = btcspv.EncodeSegWit(decodeHex("751e"), 0, &btcspv.MainNetParams)
+	suite.EqualError(err, "Version 0 witness program must be 20 or 32 bytes, got 2 bytes")
+}
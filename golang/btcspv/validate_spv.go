@@ -1,11 +1,6 @@
 package btcspv
 
-import (
-	"bytes"
-	"errors"
-
-	sdk "github.com/cosmos/cosmos-sdk/types"
-)
+import "bytes"
 
 // Prove checks the validity of a merkle proof
 func Prove(txid Hash256Digest, merkleRoot Hash256Digest, intermediateNodes []byte, index uint) bool {
@@ -22,6 +17,62 @@ func Prove(txid Hash256Digest, merkleRoot Hash256Digest, intermediateNodes []byt
 	return VerifyHash256Merkle(proof, index)
 }
 
+// merkleLevel hashes a level of a merkle tree into the level above it
+// An odd node at the end of a level is paired with itself, as in Bitcoin.
+func merkleLevel(level []Hash256Digest) []Hash256Digest {
+	next := make([]Hash256Digest, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		right := level[i]
+		if i+1 < len(level) {
+			right = level[i+1]
+		}
+		next = append(next, Hash256MerkleStep(level[i][:], right[:]))
+	}
+	return next
+}
+
+// ComputeMerkleRoot calculates the merkle root of a block's LE txids
+func ComputeMerkleRoot(txids []Hash256Digest) (Hash256Digest, error) {
+	if len(txids) == 0 {
+		return Hash256Digest{}, NewSPVError(ErrCodeBadLength, "Cannot compute the merkle root of 0 txids")
+	}
+
+	level := txids
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return level[0], nil
+}
+
+// BuildMerkleProof builds the intermediate nodes proving the txid at index
+// The txids must be in block order. Returns the intermediate nodes, ordered
+// from leaf to root, and the merkle root. These are accepted by Prove.
+func BuildMerkleProof(txids []Hash256Digest, index uint) ([]byte, Hash256Digest, error) {
+	if len(txids) == 0 {
+		return []byte{}, Hash256Digest{}, NewSPVError(ErrCodeBadLength, "Cannot build a merkle proof from 0 txids")
+	}
+	if index >= uint(len(txids)) {
+		return []byte{}, Hash256Digest{}, newSPVErrorf(ErrCodeOutOfRange, "Index %d out of range for %d txids", index, len(txids))
+	}
+
+	intermediateNodes := []byte{}
+	level := txids
+	idx := index
+
+	for len(level) > 1 {
+		sibling := idx ^ 1
+		if sibling >= uint(len(level)) {
+			sibling = idx
+		}
+		intermediateNodes = append(intermediateNodes, level[sibling][:]...)
+
+		level = merkleLevel(level)
+		idx >>= 1
+	}
+
+	return intermediateNodes, level[0], nil
+}
+
 // CalculateTxID hashes transaction to get txid
 func CalculateTxID(version, vin, vout, locktime []byte) Hash256Digest {
 	txid := []byte{}
@@ -33,11 +84,11 @@ func CalculateTxID(version, vin, vout, locktime []byte) Hash256Digest {
 }
 
 // ValidateHeaderWork checks validity of header work
-func ValidateHeaderWork(digest Hash256Digest, target sdk.Uint) bool {
-	if bytes.Equal(digest[:], bytes.Repeat([]byte{0}, 32)) {
+func ValidateHeaderWork(digest Hash256Digest, target Uint256) bool {
+	if digest == (Hash256Digest{}) {
 		return false
 	}
-	return BytesToBigUint(ReverseEndianness(digest[:])).LT(target)
+	return Uint256FromLE(digest).LT(target)
 }
 
 // ValidateHeaderPrevHash checks validity of header chain
@@ -48,38 +99,55 @@ func ValidateHeaderPrevHash(header RawHeader, prevHeaderDigest Hash256Digest) bo
 	return bytes.Equal(prevHash[:], prevHeaderDigest[:])
 }
 
+// validateChainedHeader checks a header's link to its parent and its work
+// The link is not checked for the first header of a chain. Returns the
+// header's digest and difficulty.
+func validateChainedHeader(header RawHeader, first bool, prevDigest Hash256Digest, net *NetParams) (Hash256Digest, Uint256, error) {
+	digest := Hash256(header[:])
+
+	// After the first header, check that headers are in a chain
+	if !first && !ValidateHeaderPrevHash(header, prevDigest) {
+		return digest, Uint256{}, NewSPVError(ErrCodeInvalidChain, "Header bytes not a valid chain")
+	}
+
+	difficulty, err := checkHeaderWork(header, digest, net)
+	return digest, difficulty, err
+}
+
+// checkHeaderWork checks that a header meets its own target
+// Returns the header's difficulty.
+func checkHeaderWork(header RawHeader, digest Hash256Digest, net *NetParams) (Uint256, error) {
+	// Require that the header has sufficient work
+	target := ExtractTarget(header)
+	if !ValidateHeaderWork(digest, target) {
+		return Uint256{}, NewSPVError(ErrCodeLowWork, "Header does not meet its own difficulty target")
+	}
+
+	return CalculateDifficulty(target, net), nil
+}
+
 // ValidateHeaderChain checks validity of header chain
-func ValidateHeaderChain(headers []byte) (sdk.Uint, error) {
+func ValidateHeaderChain(headers []byte, net *NetParams) (Uint256, error) {
 	// Check header chain length
 	if len(headers)%80 != 0 {
-		return sdk.ZeroUint(), errors.New("Header bytes not multiple of 80")
+		return Uint256{}, NewSPVError(ErrCodeBadLength, "Header bytes not multiple of 80")
 	}
 
 	var digest Hash256Digest
-	totalDifficulty := sdk.ZeroUint()
+	totalDifficulty := Uint256{}
 
 	for i := 0; i < len(headers)/80; i++ {
 		start := i * 80
 		end := start + 80
 		header, _ := NewRawHeader(headers[start:end:end])
 
-		// After the first header, check that headers are in a chain
-		if i != 0 {
-			if !ValidateHeaderPrevHash(header, digest) {
-				return sdk.ZeroUint(), errors.New("Header bytes not a valid chain")
-			}
-		}
-
-		// ith header target
-		target := ExtractTarget(header)
-
-		// Require that the header has sufficient work
-		digest = Hash256(header[:])
-		if !ValidateHeaderWork(digest, target) {
-			return sdk.ZeroUint(), errors.New("Header does not meet its own difficulty target")
+		var difficulty Uint256
+		var err error
+		digest, difficulty, err = validateChainedHeader(header, i == 0, digest, net)
+		if err != nil {
+			return Uint256{}, err
 		}
-
-		totalDifficulty = totalDifficulty.Add(CalculateDifficulty(target))
+		totalDifficulty = totalDifficulty.Add(difficulty)
 	}
 	return totalDifficulty, nil
 }
@@ -89,19 +157,19 @@ func (b BitcoinHeader) Validate() (bool, error) {
 	// Check that HashLE is the correct hash of the raw header
 	headerHash := Hash256(b.Raw[:])
 	if !bytes.Equal(headerHash[:], b.Hash[:]) {
-		return false, errors.New("Hash is not the correct hash of the header")
+		return false, NewSPVError(ErrCodeWrongDigest, "Hash is not the correct hash of the header")
 	}
 
 	// Check that the MerkleRootLE is the correct MerkleRoot for the header
 	extractedMerkleRootLE := ExtractMerkleRootLE(b.Raw)
 	if !bytes.Equal(extractedMerkleRootLE[:], b.MerkleRoot[:]) {
-		return false, errors.New("MerkleRoot is not the correct merkle root of the header")
+		return false, NewSPVError(ErrCodeWrongMerkleRoot, "MerkleRoot is not the correct merkle root of the header")
 	}
 
 	// Check that PrevHash is the correct PrevHash for the header
 	extractedPrevHashLE := ExtractPrevBlockHashLE(b.Raw)
 	if bytes.Compare(extractedPrevHashLE[:], b.PrevHash[:]) != 0 {
-		return false, errors.New("Prevhash is not the correct parent hash of the header")
+		return false, NewSPVError(ErrCodeWrongPrevHash, "Prevhash is not the correct parent hash of the header")
 	}
 
 	return true, nil
@@ -114,17 +182,17 @@ func (s SPVProof) Validate() (bool, error) {
 
 	validVin := ValidateVin(s.Vin)
 	if !validVin {
-		return false, errors.New("Vin is not valid")
+		return false, NewSPVError(ErrCodeInvalidVin, "Vin is not valid")
 	}
 	validVout := ValidateVout(s.Vout)
 	if !validVout {
-		return false, errors.New("Vout is not valid")
+		return false, NewSPVError(ErrCodeInvalidVout, "Vout is not valid")
 	}
 
 	// Calculate the Tx ID and compare it to the one in SPVProof
 	txid := CalculateTxID(s.Version, s.Vin, s.Vout, s.Locktime)
 	if !bytes.Equal(txid[:], s.TxID[:]) {
-		return false, errors.New("Version, Vin, Vout and Locktime did not yield correct TxID")
+		return false, NewSPVError(ErrCodeTxIDMismatch, "Version, Vin, Vout and Locktime did not yield correct TxID")
 	}
 
 	// Validate all the fields in ConfirmingHeader
@@ -136,9 +204,101 @@ func (s SPVProof) Validate() (bool, error) {
 	// Check that the proof is valid
 	validProof := Prove(s.TxID, s.ConfirmingHeader.MerkleRoot, intermediateNodes, index)
 	if !validProof {
-		return false, errors.New("Merkle Proof is not valid")
+		return false, NewSPVError(ErrCodeBadMerkleProof, "Merkle Proof is not valid")
 	}
 
 	// If there are no errors, return true
 	return true, nil
 }
+
+// ValidateHeaderChainRetarget checks validity of a header chain extending a trusted anchor
+// In addition to the checks in ValidateHeaderChain, nBits must stay constant
+// within each difficulty epoch, and must equal the compact-encoded
+// RetargetAlgorithm output at each epoch boundary. Returns the total work of
+// the headers, as summed by CalculateWork into Core's chainwork, not including
+// the anchor.
+//
+// On networks that allow min-difficulty blocks, a header may instead use
+// net.PowLimitBits if it is more than twice net.TargetSpacing after its
+// parent. The anchor may be a min-difficulty header, as the epoch target is
+// taken from anchor.EpochTarget, and the next retarget on testnet3 from
+// anchor.Bits.
+func ValidateHeaderChainRetarget(anchor ChainAnchor, headers []byte, net *NetParams) (Uint256, error) {
+	// Check header chain length
+	if len(headers)%80 != 0 {
+		return Uint256{}, NewSPVError(ErrCodeBadLength, "Header bytes not multiple of 80")
+	}
+
+	// Core retargets from the truncated target in the previous header's nBits
+	epochBits := TargetToCompact(anchor.EpochTarget, false)
+	epochTarget, _, _ := CompactToTarget(epochBits)
+	epochStart := anchor.EpochStart
+	bits := anchor.Bits
+	if bits == 0 {
+		bits = epochBits
+	}
+
+	digest := anchor.Digest
+	timestamp := anchor.Timestamp
+	totalWork := Uint256{}
+
+	for i := 0; i < len(headers)/80; i++ {
+		start := i * 80
+		end := start + 80
+		header, _ := NewRawHeader(headers[start:end:end])
+		height := anchor.Height + uint32(i) + 1
+
+		prevDigest := digest
+		prevTimestamp := timestamp
+		prevBits := bits
+		digest = Hash256(header[:])
+		bits = ExtractBits(header)
+		timestamp = ExtractTimestamp(header)
+
+		if !ValidateHeaderPrevHash(header, prevDigest) {
+			return Uint256{}, &HeaderError{i, height, digest,
+				NewSPVError(ErrCodeInvalidChain, "Header bytes not a valid chain")}
+		}
+
+		// The first header of each epoch sets a new target, as in Core's
+		// GetNextWorkRequired
+		expectedBits := epochBits
+		if height%net.RetargetInterval == 0 {
+			if net.NoRetargeting {
+				epochBits = prevBits
+			} else {
+				// Before BIP94, retargets start from the last header of the
+				// epoch, which may be a min-difficulty header on testnet3
+				lastTarget, _, _ := CompactToTarget(prevBits)
+				if net.EnforceBIP94 {
+					lastTarget = epochTarget
+				}
+				newTarget := RetargetAlgorithm(lastTarget, epochStart, prevTimestamp, net)
+				if newTarget.GT(net.PowLimit) {
+					newTarget = net.PowLimit
+				}
+				epochBits = TargetToCompact(newTarget, false)
+			}
+			epochTarget, _, _ = CompactToTarget(epochBits)
+			epochStart = timestamp
+			expectedBits = epochBits
+		} else if net.AllowMinDifficultyBlocks && timestamp > prevTimestamp+2*uint(net.TargetSpacing) {
+			expectedBits = net.PowLimitBits
+		}
+
+		if bits != expectedBits {
+			return Uint256{}, &HeaderError{i, height, digest,
+				NewSPVError(ErrCodeUnexpectedDifficultyChange, "Header nBits do not match the expected difficulty")}
+		}
+
+		target := ExtractTarget(header)
+		if !ValidateHeaderWork(digest, target) {
+			return Uint256{}, &HeaderError{i, height, digest,
+				NewSPVError(ErrCodeLowWork, "Header does not meet its own difficulty target")}
+		}
+
+		totalWork = totalWork.Add(CalculateWork(target))
+	}
+
+	return totalWork, nil
+}
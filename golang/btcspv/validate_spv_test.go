@@ -1,7 +1,9 @@
 package btcspv_test
 
 import (
-	sdk "github.com/cosmos/cosmos-sdk/types"
+	"encoding/hex"
+	"errors"
+
 	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
 )
 
@@ -22,6 +24,60 @@ func (suite *UtilsSuite) TestProve() {
 	}
 }
 
+func (suite *UtilsSuite) TestBuildMerkleProof() {
+	for n := 1; n <= 17; n++ {
+		txids := make([]Hash256Digest, n)
+		for i := range txids {
+			txids[i] = btcspv.Hash256([]byte{byte(n), byte(i)})
+		}
+
+		root, err := btcspv.ComputeMerkleRoot(txids)
+		suite.Nil(err)
+
+		for i := range txids {
+			nodes, proofRoot, err := btcspv.BuildMerkleProof(txids, uint(i))
+			suite.Nil(err)
+			suite.Equal(root, proofRoot)
+			suite.True(btcspv.Prove(txids[i], root, nodes, uint(i)))
+
+			// The proof does not verify with its first pair swapped, unless
+			// the txid is an odd node paired with itself
+			if i^1 < n {
+				suite.False(btcspv.Prove(txids[i], root, nodes, uint(i^1)))
+			}
+		}
+
+		_, _, err = btcspv.BuildMerkleProof(txids, uint(n))
+		suite.True(errors.Is(err, btcspv.ErrOutOfRange))
+	}
+
+	_, err := btcspv.ComputeMerkleRoot([]Hash256Digest{})
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+	_, _, err = btcspv.BuildMerkleProof([]Hash256Digest{}, 0)
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+}
+
+func (suite *UtilsSuite) TestComputeMerkleRoot() {
+	// Block 100000, with txids and root in RPC (BE) byte order
+	txids := []string{
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	}
+	leaves := []Hash256Digest{}
+	for _, txid := range txids {
+		buf, _ := hex.DecodeString(txid)
+		leaf, _ := btcspv.NewHash256Digest(btcspv.ReverseEndianness(buf))
+		leaves = append(leaves, leaf)
+	}
+
+	root, err := btcspv.ComputeMerkleRoot(leaves)
+	suite.Nil(err)
+	suite.Equal("f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+		hex.EncodeToString(btcspv.ReverseEndianness(root[:])))
+}
+
 func (suite *UtilsSuite) TestCalculateTxID() {
 	fixture := suite.Fixtures.CalculateTxID
 
@@ -74,8 +130,8 @@ func (suite *UtilsSuite) TestValidateHeaderChain() {
 
 	for i := range fixture {
 		testCase := fixture[i]
-		expected := sdk.NewUint(testCase.Output)
-		actual, err := btcspv.ValidateHeaderChain(testCase.Input)
+		expected := btcspv.NewUint256(testCase.Output)
+		actual, err := btcspv.ValidateHeaderChain(testCase.Input, &btcspv.MainNetParams)
 		suite.Nil(err)
 		suite.Equal(expected, actual)
 	}
@@ -84,8 +140,86 @@ func (suite *UtilsSuite) TestValidateHeaderChain() {
 
 	for i := range fixtureError {
 		testCase := fixtureError[i]
-		actual, err := btcspv.ValidateHeaderChain(testCase.Input)
-		suite.Equal(actual, sdk.NewUint(0))
+		actual, err := btcspv.ValidateHeaderChain(testCase.Input, &btcspv.MainNetParams)
+		suite.Equal(actual, btcspv.Uint256{})
 		suite.EqualError(err, testCase.ErrorMessage)
 	}
 }
+
+func (suite *UtilsSuite) TestValidateHeaderChainRetarget() {
+	fixture := suite.Fixtures.RetargetAlgorithm
+
+	for i := range fixture {
+		first := fixture[i].Input[0]
+		last := fixture[i].Input[1]
+		next := fixture[i].Input[2]
+
+		// Skip cases where the third header does not follow the second
+		lastHeader := btcspv.HeaderFromRaw(last.Hex, last.Height)
+		if !btcspv.ValidateHeaderPrevHash(next.Hex, lastHeader.Hash) {
+			continue
+		}
+
+		anchor := btcspv.AnchorFromHeader(lastHeader, first.Timestamp)
+		actual, err := btcspv.ValidateHeaderChainRetarget(anchor, next.Hex[:], &btcspv.MainNetParams)
+		suite.Nil(err)
+		suite.Equal(btcspv.CalculateWork(btcspv.ExtractTarget(next.Hex)), actual)
+
+		// The same header is invalid mid-epoch, where nBits must not change
+		anchor.Height++
+		_, err = btcspv.ValidateHeaderChainRetarget(anchor, next.Hex[:], &btcspv.MainNetParams)
+		suite.True(errors.Is(err, btcspv.ErrUnexpectedDifficultyChange))
+
+		var headerErr *btcspv.HeaderError
+		suite.True(errors.As(err, &headerErr))
+		suite.Equal(0, headerErr.Index)
+		suite.Equal(next.Height+1, headerErr.Height)
+		suite.Equal(btcspv.Hash256(next.Hex[:]), headerErr.Digest)
+
+		// A forger cannot choose an easier target at the boundary
+		anchor.Height--
+		anchor.EpochStart = first.Timestamp - 1000000
+		_, err = btcspv.ValidateHeaderChainRetarget(anchor, next.Hex[:], &btcspv.MainNetParams)
+		suite.True(errors.Is(err, btcspv.ErrUnexpectedDifficultyChange))
+	}
+}
+
+func (suite *UtilsSuite) TestValidateHeaderChainRetargetMidEpoch() {
+	fixture := suite.Fixtures.ValidateHeaderChain
+
+	for i := range fixture {
+		headers := fixture[i].Input
+		firstHeader, _ := btcspv.NewRawHeader(headers[:80])
+
+		anchor := btcspv.ChainAnchor{
+			Digest:      btcspv.ExtractPrevBlockHashLE(firstHeader),
+			Height:      100,
+			EpochTarget: btcspv.ExtractTarget(firstHeader),
+		}
+
+		expected := btcspv.Uint256{}
+		for j := 0; j < len(headers); j += 80 {
+			header, _ := btcspv.NewRawHeader(headers[j : j+80])
+			expected = expected.Add(btcspv.CalculateWork(btcspv.ExtractTarget(header)))
+		}
+
+		actual, err := btcspv.ValidateHeaderChainRetarget(anchor, headers, &btcspv.MainNetParams)
+		suite.Nil(err)
+		suite.Equal(expected, actual)
+
+		// Move the anchor so that the chain crosses an epoch boundary
+		anchor.Height = 2014
+		_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &btcspv.MainNetParams)
+		suite.EqualError(err, "Header nBits do not match the expected difficulty at index 1 (height 2016)")
+
+		// Break the chain linkage
+		anchor.Height = 100
+		anchor.Digest = Hash256Digest{}
+		_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers, &btcspv.MainNetParams)
+		suite.True(errors.Is(err, btcspv.ErrInvalidChain))
+		suite.EqualError(err, "Header bytes not a valid chain at index 0 (height 101)")
+
+		_, err = btcspv.ValidateHeaderChainRetarget(anchor, headers[1:], &btcspv.MainNetParams)
+		suite.True(errors.Is(err, btcspv.ErrBadLength))
+	}
+}
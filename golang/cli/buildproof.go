@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// BuildProofResult is the result of buildProof
+// Its text form is the indented SPVProof JSON document.
+type BuildProofResult struct {
+	btcspv.SPVProof
+}
+
+// Text formats the proof as an indented JSON document
+func (r BuildProofResult) Text() string {
+	encoded, _ := json.MarshalIndent(r.SPVProof, "", "  ")
+	return string(encoded) + "\n"
+}
+
+// BuildProof builds an SPVProof for a transaction in a raw block
+// Usage: buildProof [-height n] <block> <txid>
+// The block is hex, @file or - for stdin, e.g. the output of
+// `bitcoin-cli getblock <hash> 0`. The txid is big-endian, as displayed by
+// bitcoind. height sets the confirming header's height.
+func BuildProof(args []string, stdin io.Reader) (Result, error) {
+	flags := flag.NewFlagSet("buildProof", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	height := flags.Uint("height", 0, "height of the block")
+	if err := flags.Parse(args); err != nil {
+		return nil, usageError{fmt.Sprintf("buildProof: %s", err)}
+	}
+	if flags.NArg() != 2 {
+		return nil, usageError{"Usage: buildProof [-height n] <block> <txid>"}
+	}
+
+	decoded, err := hex.DecodeString(btcspv.Strip0xPrefix(flags.Arg(1)))
+	if err != nil {
+		return nil, usageError{fmt.Sprintf("Invalid txid: %s", err)}
+	}
+	txidBE, err := btcspv.NewHash256Digest(decoded)
+	if err != nil {
+		return nil, usageError{fmt.Sprintf("Invalid txid: %s", err)}
+	}
+
+	raw, err := readArg(flags.Arg(0), stdin)
+	if err != nil {
+		return nil, err
+	}
+	block, err := btcspv.ParseBlock(raw, uint32(*height))
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := block.ProveTx(btcspv.ReverseHash256Endianness(txidBE))
+	if err != nil {
+		return nil, err
+	}
+	return BuildProofResult{proof}, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// DecodeTxResult is the result of decodeTx
+// TxID and WTxID are big-endian, as displayed by bitcoind.
+type DecodeTxResult struct {
+	TxID     btcspv.Hash256Digest `json:"tx_id"`
+	WTxID    btcspv.Hash256Digest `json:"wtx_id"`
+	Version  uint32               `json:"version"`
+	Locktime uint32               `json:"locktime"`
+	Inputs   []InputInfo          `json:"inputs"`
+	Outputs  []OutputInfo         `json:"outputs"`
+}
+
+// Text formats the transaction, then each input and output
+func (r DecodeTxResult) Text() string {
+	dataStr := fmt.Sprintf(
+		"\nTransaction:\n  TxID: %s,\n  WTxID: %s,\n  Version: %d,\n  Locktime: %d\n",
+		hex.EncodeToString(r.TxID[:]), hex.EncodeToString(r.WTxID[:]), r.Version, r.Locktime)
+	dataStr += VinResult{r.Inputs}.Text()
+	dataStr += VoutResult{r.Outputs}.Text()
+	return dataStr
+}
+
+// DecodeTx decodes a raw legacy or segwit transaction
+// Usage: decodeTx <tx>
+// The transaction is hex, @file or - for stdin. Inputs and outputs are
+// classified as by parseVin and parseVout.
+func DecodeTx(args []string, net *btcspv.NetParams, stdin io.Reader) (Result, error) {
+	raw, err := readArg(args[0], stdin)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := btcspv.ParseTransaction(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	vin, err := ParseVin(tx.Vin())
+	if err != nil {
+		return nil, err
+	}
+	// Outputs are classified one by one, as ParseVout rejects vouts of more
+	// than 252 outputs
+	outputs := []OutputInfo{}
+	for i := range tx.Outputs {
+		info, err := parseOutput(tx.Outputs[i].Serialize(), net)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, info)
+	}
+
+	return DecodeTxResult{
+		TxID:     btcspv.ReverseHash256Endianness(tx.TxID),
+		WTxID:    btcspv.ReverseHash256Endianness(tx.WTxID),
+		Version:  tx.Version,
+		Locktime: tx.Locktime,
+		Inputs:   vin.Inputs,
+		Outputs:  outputs,
+	}, nil
+}
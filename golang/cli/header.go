@@ -3,70 +3,72 @@ package main
 import (
 	"encoding/hex"
 	"fmt"
-	"strconv"
 	"time"
 
-	sdk "github.com/cosmos/cosmos-sdk/types"
 	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
 )
 
-func prettifyHeaderData(
-	num uint,
-	digest btcspv.Hash256Digest,
-	version uint,
-	prevHash btcspv.Hash256Digest,
-	merkleRoot btcspv.Hash256Digest,
-	timestamp uint,
-	target sdk.Uint,
-	nonce uint) string {
+// HeaderInfo is human-readable information about a header
+// As in the text output, PrevHash and MerkleRoot are little-endian.
+type HeaderInfo struct {
+	Digest     btcspv.Hash256Digest `json:"digest"`
+	Version    uint                 `json:"version"`
+	PrevHash   btcspv.Hash256Digest `json:"prev_hash"`
+	MerkleRoot btcspv.Hash256Digest `json:"merkle_root"`
+	Timestamp  uint                 `json:"timestamp"`
+	Target     btcspv.Uint256       `json:"target"`
+	Nonce      uint                 `json:"nonce"`
+}
+
+// Text formats the header
+func (h HeaderInfo) Text() string {
+	return prettifyHeaderData(0, h)
+}
 
+func prettifyHeaderData(num uint, header HeaderInfo) string {
 	// Convert byte arrays to readable hex strings
-	digestStr := hex.EncodeToString(digest[:])
-	prevHashStr := hex.EncodeToString(prevHash[:])
-	merkleRootStr := hex.EncodeToString(merkleRoot[:])
+	digestStr := hex.EncodeToString(header.Digest[:])
+	prevHashStr := hex.EncodeToString(header.PrevHash[:])
+	merkleRootStr := hex.EncodeToString(header.MerkleRoot[:])
 
 	// Convert timestamp to readable time
-	timestampStr := strconv.Itoa(int(timestamp))
-	unixIntValue, err := strconv.ParseInt(timestampStr, 10, 64)
-	if err != nil {
-		return fmt.Sprintf("%s\n", err)
-	}
-	timeStr := time.Unix(unixIntValue, 0)
+	timeStr := time.Unix(int64(header.Timestamp), 0)
 
 	// Return data in a formatted string
 	dataStr := fmt.Sprintf(
-		"\nHeader #%d:\n  Digest: %s,\n  Version: %d,\n  Prev Hash: %s,\n  Merkle Root: %s,\n  Time Stamp: %s,\n  Target: %d,\n  Nonce: %d\n",
-		num, digestStr, version, prevHashStr, merkleRootStr, timeStr, target, nonce)
+		"\nHeader #%d:\n  Digest: %s,\n  Version: %d,\n  Prev Hash: %s,\n  Merkle Root: %s,\n  Time Stamp: %s,\n  Target: %s,\n  Nonce: %d\n",
+		num, digestStr, header.Version, prevHashStr, merkleRootStr, timeStr, header.Target, header.Nonce)
 
 	return dataStr
 }
 
 // ParseHeader takes in a header and returns information about that header: digest, version, previous header hash, merkle root, timestamp, target and nonce
-func ParseHeader(header btcspv.RawHeader) string {
-	// Get information about the header using ParseHeader
-	digest, version, prevHash, merkleRoot, timestamp, target, nonce, err := parseHeader(header)
-	// Check for errors
+func ParseHeader(header []byte) (HeaderInfo, error) {
+	rawHeader, err := btcspv.NewRawHeader(header)
 	if err != nil {
-		return fmt.Sprintf("%s\n", err)
+		return HeaderInfo{}, err
 	}
+	return parseHeader(rawHeader), nil
+}
 
-	// Format data using prettifyHeaderData
-	headerData := prettifyHeaderData(
-		0, digest, version, prevHash, merkleRoot, timestamp, target, nonce)
-	return headerData
+// ChainResult is the result of validateHeaderChain
+type ChainResult struct {
+	TotalDifficulty btcspv.Uint256 `json:"total_difficulty"`
+}
+
+// Text formats the total difficulty
+func (r ChainResult) Text() string {
+	return fmt.Sprintf("\nTotal Difficulty: %s\n", r.TotalDifficulty)
 }
 
 // ValidateHeaderChain takes in a chain of headers as a byte array, validates the chain, and returns the total difficulty
-func ValidateHeaderChain(headers []byte) string {
+func ValidateHeaderChain(headers []byte, net *btcspv.NetParams) (ChainResult, error) {
 	// Get the total difficulty using ValidateHeaderChain
-	totalDifficulty, err := btcspv.ValidateHeaderChain(headers)
-	// Check for errors
+	totalDifficulty, err := btcspv.ValidateHeaderChain(headers, net)
 	if err != nil {
-		return fmt.Sprintf("%s\n", err)
+		return ChainResult{}, err
 	}
-
-	// Return the total difficulty
-	return fmt.Sprintf("\nTotal Difficulty: %d\n", totalDifficulty)
+	return ChainResult{TotalDifficulty: totalDifficulty}, nil
 }
 
 // ExtractMerkleRootBE returns the transaction merkle root from a given block header
@@ -82,16 +84,16 @@ func ExtractPrevBlockHashBE(header btcspv.RawHeader) btcspv.Hash256Digest {
 }
 
 // ParseHeader parses a block header struct from a bytestring
-func parseHeader(header btcspv.RawHeader) (btcspv.Hash256Digest, uint, btcspv.Hash256Digest, btcspv.Hash256Digest, uint, sdk.Uint, uint, error) {
+func parseHeader(header btcspv.RawHeader) HeaderInfo {
 	digestLE := btcspv.Hash256(header[:])
 
-	digest := btcspv.ReverseHash256Endianness(digestLE)
-	version := btcspv.BytesToUint(btcspv.ReverseEndianness(header[0:4:4]))
-	prevHash := btcspv.ExtractPrevBlockHashLE(header)
-	merkleRoot := btcspv.ExtractMerkleRootLE(header)
-	timestamp := btcspv.ExtractTimestamp(header)
-	target := btcspv.ExtractTarget(header)
-	nonce := btcspv.BytesToUint(btcspv.ReverseEndianness(header[76:80:80]))
-
-	return digest, version, prevHash, merkleRoot, timestamp, target, nonce, nil
+	return HeaderInfo{
+		Digest:     btcspv.ReverseHash256Endianness(digestLE),
+		Version:    btcspv.BytesToUint(btcspv.ReverseEndianness(header[0:4:4])),
+		PrevHash:   btcspv.ExtractPrevBlockHashLE(header),
+		MerkleRoot: btcspv.ExtractMerkleRootLE(header),
+		Timestamp:  btcspv.ExtractTimestamp(header),
+		Target:     btcspv.ExtractTarget(header),
+		Nonce:      btcspv.BytesToUint(btcspv.ReverseEndianness(header[76:80:80])),
+	}
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// readSource reads an @file or - (stdin) argument
+// ok is false if the argument is neither. stdin is nil in batch mode, where
+// it holds the requests.
+func readSource(arg string, stdin io.Reader) ([]byte, bool, error) {
+	switch {
+	case arg == "-":
+		if stdin == nil {
+			return nil, true, usageError{"Cannot read an argument from stdin in batch mode"}
+		}
+		contents, err := ioutil.ReadAll(stdin)
+		return contents, true, err
+	case strings.HasPrefix(arg, "@"):
+		contents, err := ioutil.ReadFile(arg[1:])
+		return contents, true, err
+	default:
+		return nil, false, nil
+	}
+}
+
+// isHexText returns true if s holds only hex digits
+func isHexText(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// readArg reads a bytestring argument
+// The argument is hex, @file or - for stdin. Files and stdin may hold hex, as
+// printed by bitcoind, or raw bytes. Whitespace is dropped from hex, so it may
+// be wrapped across lines. Contents are raw bytes only if they are not hex
+// digits once whitespace is dropped.
+func readArg(arg string, stdin io.Reader) ([]byte, error) {
+	contents, ok, err := readSource(arg, stdin)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		decoded, err := hex.DecodeString(btcspv.Strip0xPrefix(arg))
+		if err != nil {
+			return nil, usageError{fmt.Sprintf("Invalid hex: %s", err)}
+		}
+		return decoded, nil
+	}
+
+	text := btcspv.Strip0xPrefix(strings.Join(strings.Fields(string(contents)), ""))
+	if !isHexText(text) {
+		return contents, nil
+	}
+	decoded, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, usageError{fmt.Sprintf("Invalid hex: %s", err)}
+	}
+	return decoded, nil
+}
+
+// readArgs reads bytestring arguments with readArg
+// Only one argument may be read from stdin.
+func readArgs(args []string, stdin io.Reader) ([][]byte, error) {
+	fromStdin := 0
+	for _, arg := range args {
+		if arg == "-" {
+			fromStdin++
+		}
+	}
+	if fromStdin > 1 {
+		return nil, usageError{"Only one argument may be read from stdin"}
+	}
+
+	decoded := make([][]byte, len(args))
+	for i, arg := range args {
+		b, err := readArg(arg, stdin)
+		if err != nil {
+			return nil, prefixError(fmt.Sprintf("Argument %d", i+1), err)
+		}
+		decoded[i] = b
+	}
+	return decoded, nil
+}
+
+// prefixError prefixes the message of err, keeping usage errors usage errors
+func prefixError(prefix string, err error) error {
+	var usage usageError
+	if errors.As(err, &usage) {
+		return usageError{fmt.Sprintf("%s: %s", prefix, usage.msg)}
+	}
+	return fmt.Errorf("%s: %s", prefix, err)
+}
@@ -2,10 +2,22 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"strconv"
 
 	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
 )
 
+// ProveResult is the result of prove
+type ProveResult struct {
+	Valid bool `json:"valid"`
+}
+
+// Text states whether the proof is valid
+func (r ProveResult) Text() string {
+	return fmt.Sprintf("\nValid proof: %t\n", r.Valid)
+}
+
 // Prove checks the validity of a merkle proof
 // Note that `index` is not a reliable indicator of location within a block.
 func Prove(
@@ -15,13 +27,36 @@ func Prove(
 	locktime []byte,
 	merkleRoot btcspv.Hash256Digest,
 	intermediateNodes []byte,
-	index uint) string {
+	index uint) ProveResult {
 	// Calculate the tx id
 	txid := btcspv.CalculateTxID(version, vin, vout, locktime)
 
 	// Check if the merkle proof is valid using Prove
 	valid := btcspv.Prove(txid, merkleRoot, intermediateNodes, index)
 
-	// Returns string stating if proof is valid or not
-	return fmt.Sprintf("\nValid proof: %t\n", valid)
+	return ProveResult{Valid: valid}
+}
+
+// runProve parses the arguments of prove
+// The index is decimal. The other arguments are bytestrings, read by readArgs.
+// The result is reported along with an error if the proof is invalid.
+func runProve(args []string, net *btcspv.NetParams, stdin io.Reader) (Result, error) {
+	index, err := strconv.ParseUint(args[6], 10, 32)
+	if err != nil {
+		return nil, usageError{fmt.Sprintf("Invalid index: %s", err)}
+	}
+
+	arguments, err := readArgs(args[:6], stdin)
+	if err != nil {
+		return nil, err
+	}
+	merkleRoot, err := btcspv.NewHash256Digest(arguments[4])
+	if err != nil {
+		return nil, err
+	}
+	result := Prove(arguments[0], arguments[1], arguments[2], arguments[3], merkleRoot, arguments[5], uint(index))
+	if !result.Valid {
+		return result, btcspv.NewSPVError(btcspv.ErrCodeBadMerkleProof, "Merkle Proof is not valid")
+	}
+	return result, nil
 }
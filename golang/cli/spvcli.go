@@ -1,63 +1,289 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"sort"
+	"strings"
 
 	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
 )
 
-func route(command string, arguments [][]byte) string {
-	var result string
-
-	switch command {
-	case "parseVin":
-		result = ParseVin(arguments[0])
-	case "parseVout":
-		result = ParseVout(arguments[0])
-	case "parseHeader":
-		rawHeader, _ := btcspv.NewRawHeader(arguments[0])
-		result = ParseHeader(rawHeader)
-	case "validateHeaderChain":
-		result = ValidateHeaderChain(arguments[0])
-	case "prove":
-		// convert argument to a uint
-		str := string(arguments[6])
-		uint64Arg, err := strconv.ParseUint(str, 10, 32)
+// Exit codes. Bad invocations exit with exitUsage, and commands that fail,
+// e.g. on malformed input, exit with exitFailure.
+const (
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// Result is the output of a command
+// Text formats it for people. With -o json, it is marshalled as JSON instead.
+type Result interface {
+	Text() string
+}
+
+// usageError is an error in how the CLI was invoked
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// command is a CLI command
+// nArgs is the number of arguments it takes, or -1 if it parses its own flags
+// and arguments. A partial command may return a result along with an error,
+// e.g. verifyProof reports every proof, then fails if any is invalid.
+type command struct {
+	usage   string
+	nArgs   int
+	partial bool
+	run     func(args []string, net *btcspv.NetParams, stdin io.Reader) (Result, error)
+}
+
+// withBytes adapts a command whose arguments are all bytestrings
+func withBytes(f func(args [][]byte, net *btcspv.NetParams) (Result, error)) func([]string, *btcspv.NetParams, io.Reader) (Result, error) {
+	return func(args []string, net *btcspv.NetParams, stdin io.Reader) (Result, error) {
+		arguments, err := readArgs(args, stdin)
 		if err != nil {
-			return fmt.Sprintf("%s\n", err)
+			return nil, err
 		}
-		uintArg := uint(uint64Arg)
-		merkleRoot, _ := btcspv.NewHash256Digest(arguments[4])
-		result = Prove(arguments[0], arguments[1], arguments[2], arguments[3], merkleRoot, arguments[5], uintArg)
-	default:
-		result = fmt.Sprintf("Unknown command: %s\n", command)
+		return f(arguments, net)
 	}
+}
 
-	return result
+var commands = map[string]command{
+	"parseVin": {
+		usage: "parseVin <vin>",
+		nArgs: 1,
+		run: withBytes(func(args [][]byte, net *btcspv.NetParams) (Result, error) {
+			return ParseVin(args[0])
+		}),
+	},
+	"parseVout": {
+		usage: "parseVout <vout>",
+		nArgs: 1,
+		run: withBytes(func(args [][]byte, net *btcspv.NetParams) (Result, error) {
+			return ParseVout(args[0], net)
+		}),
+	},
+	"parseHeader": {
+		usage: "parseHeader <header>",
+		nArgs: 1,
+		run: withBytes(func(args [][]byte, net *btcspv.NetParams) (Result, error) {
+			return ParseHeader(args[0])
+		}),
+	},
+	"validateHeaderChain": {
+		usage: "validateHeaderChain <headers>",
+		nArgs: 1,
+		run: withBytes(func(args [][]byte, net *btcspv.NetParams) (Result, error) {
+			return ValidateHeaderChain(args[0], net)
+		}),
+	},
+	"prove": {
+		usage:   "prove <version> <vin> <vout> <locktime> <merkleRoot> <intermediateNodes> <index>",
+		nArgs:   7,
+		partial: true,
+		run:     runProve,
+	},
+	"verifyProof": {
+		usage:   "verifyProof [-headers hex] [-confirmations n] <file|@file|->",
+		nArgs:   -1,
+		partial: true,
+		run:     VerifyProof,
+	},
+	"buildProof": {
+		usage: "buildProof [-height n] <block> <txid>",
+		nArgs: -1,
+		run: func(args []string, net *btcspv.NetParams, stdin io.Reader) (Result, error) {
+			return BuildProof(args, stdin)
+		},
+	},
+	"decodeTx": {
+		usage: "decodeTx <tx>",
+		nArgs: 1,
+		run:   DecodeTx,
+	},
 }
 
-// Map function to slice of strings
-func Map(vs []string, f func(string) []byte) [][]byte {
-	vsm := make([][]byte, len(vs))
-	for i, v := range vs {
-		vsm[i] = f(v)
+// usage lists the commands and their arguments
+func usage() string {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{
+		"Usage: spvcli [-net name] [-o text|json] [-batch] <command> [arguments]",
+		"Bytestring arguments are hex, @file, or - for stdin.",
+		"Commands:",
+	}
+	for _, name := range names {
+		lines = append(lines, "  "+commands[name].usage)
 	}
-	return vsm
+	return strings.Join(lines, "\n")
 }
 
-func main() {
-	var result string
+// route runs a command
+// The result is nil on error, except for partial commands.
+func route(name string, args []string, net *btcspv.NetParams, stdin io.Reader) (Result, error) {
+	cmd, ok := commands[name]
+	if !ok {
+		return nil, usageError{fmt.Sprintf("Unknown command: %s", name)}
+	}
+	if cmd.nArgs >= 0 && len(args) != cmd.nArgs {
+		return nil, usageError{fmt.Sprintf("Usage: spvcli [flags] %s", cmd.usage)}
+	}
 
-	if len(os.Args) < 2 {
-		fmt.Print("Not enough arguments\n")
+	result, err := cmd.run(args, net, stdin)
+	if err != nil && !cmd.partial {
+		return nil, err
+	}
+	return result, err
+}
+
+// writeResult writes a result to w in the output format
+func writeResult(w io.Writer, result Result, format string) error {
+	if format == "json" {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", encoded)
+		return err
+	}
+	_, err := fmt.Fprint(w, result.Text())
+	return err
+}
+
+// writeError writes an error to w in the output format
+// In JSON, errors are written as {"error": "message"}.
+func writeError(w io.Writer, err error, format string) {
+	if format == "json" {
+		encoded, _ := json.Marshal(struct {
+			Error string `json:"error"`
+		}{err.Error()})
+		fmt.Fprintf(w, "%s\n", encoded)
 		return
 	}
+	fmt.Fprintf(w, "%s\n", err)
+}
+
+// exitCode returns the exit code for a command's error
+func exitCode(err error) int {
+	var usage usageError
+	if errors.As(err, &usage) {
+		return exitUsage
+	}
+	return exitFailure
+}
+
+// runBatch runs newline-delimited requests read from r
+// Each request is a command and its arguments, separated by whitespace. Blank
+// lines and lines starting with # are skipped. r holds the requests, so
+// arguments cannot be read from stdin.
+//
+// In JSON mode, every request writes one line to w, its result or its error,
+// so output lines match requests. Errors are also written to errW with their
+// line number. It returns exitFailure if any request failed.
+func runBatch(r io.Reader, w io.Writer, errW io.Writer, net *btcspv.NetParams, format string) int {
+	code := 0
+	reader := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			writeError(errW, readErr, format)
+			return exitFailure
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 0 && !strings.HasPrefix(fields[0], "#") {
+			result, err := route(fields[0], fields[1:], net, nil)
+			if result != nil {
+				if err := writeResult(w, result, format); err != nil {
+					writeError(errW, err, format)
+					return exitFailure
+				}
+			} else if format == "json" {
+				writeError(w, err, format)
+			}
+			if err != nil {
+				writeError(errW, fmt.Errorf("line %d: %s", lineNumber, err), format)
+				code = exitFailure
+			}
+		}
+
+		if readErr == io.EOF {
+			return code
+		}
+	}
+}
 
-	command := os.Args[1]
-	arguments := Map(os.Args[2:], btcspv.DecodeIfHex)
+// run executes the CLI and returns the exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("spvcli", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, usage())
+		flags.PrintDefaults()
+	}
+	netName := flags.String("net", "mainnet", "network: mainnet, testnet3, testnet4, signet or regtest")
+	format := flags.String("o", "text", "output format: text or json")
+	jsonOutput := flags.Bool("json", false, "shorthand for -o json")
+	batch := flags.Bool("batch", false, "run newline-delimited requests from stdin")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *jsonOutput {
+		*format = "json"
+	}
+	if *format != "text" && *format != "json" {
+		writeError(stderr, fmt.Errorf("Unknown output format: %s", *format), "text")
+		return exitUsage
+	}
 
-	result = route(command, arguments)
-	fmt.Print(result)
+	net, err := btcspv.NetParamsByName(*netName)
+	if err != nil {
+		writeError(stderr, err, *format)
+		return exitUsage
+	}
+
+	if *batch {
+		if flags.NArg() != 0 {
+			writeError(stderr, errors.New("Batch mode reads requests from stdin, and takes no arguments"), *format)
+			return exitUsage
+		}
+		return runBatch(stdin, stdout, stderr, net, *format)
+	}
+
+	if flags.NArg() < 1 {
+		writeError(stderr, fmt.Errorf("Not enough arguments\n%s", usage()), *format)
+		return exitUsage
+	}
+
+	// A partial command may return a result along with an error
+	result, err := route(flags.Arg(0), flags.Args()[1:], net, stdin)
+	if result != nil {
+		if err := writeResult(stdout, result, *format); err != nil {
+			writeError(stderr, err, *format)
+			return exitFailure
+		}
+	}
+	if err != nil {
+		writeError(stderr, err, *format)
+		return exitCode(err)
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+const (
+	header   = "0000002073bd2184edd9c4fc76642ea6754ee40136970efc10c4190000000000000000000296ef123ea96da5cf695f22bf7d94be87d49db1ad7ac371ac43c4da4161c8c216349c5ba11928170d38782b"
+	version  = "01000000"
+	vin      = "011746bd867400f3494b8f44c24b83e1aa58c4f0ff25b4a61cffeffd4bc0f9ba300000000000ffffffff"
+	vout     = "024897070000000000220020a4333e5612ab1a1043b25755c89b16d55184a42f81799e623e6bc39db8539c180000000000000000166a14edb1b5c2f39af0fec151732585b1049b07895211"
+	locktime = "00000000"
+)
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// writeFile writes contents to a file in dir, returning an @file argument
+func writeFile(t *testing.T, dir string, name string, contents []byte) string {
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, contents, 0644))
+	return "@" + path
+}
+
+// runCLI runs the CLI, returning its exit code, stdout and stderr
+func runCLI(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spvcli")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	wrapped := header[:80] + "\n" + header[80:] + "\n"
+
+	// A one-transaction block, so the merkle root is the txid
+	txid := btcspv.CalculateTxID(decodeHex(version), decodeHex(vin), decodeHex(vout), decodeHex(locktime))
+	txidBE := btcspv.ReverseHash256Endianness(txid)
+	rawHeader := decodeHex(header)
+	copy(rawHeader[36:68], txid[:])
+	block := append(rawHeader, 0x01)
+	block = append(block, decodeHex(version+vin+vout+locktime)...)
+
+	cases := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"parseVin", []string{"-json", "parseVin", vin}, "", 0, `"type":"WITNESS"`, ""},
+		{"parseVout", []string{"-json", "parseVout", vout}, "", 0, `"type":"WSH"`, ""},
+		{"parseHeader", []string{"-o", "json", "parseHeader", "0x" + header}, "", 0, `"digest"`, ""},
+		{"validateHeaderChain", []string{"-json", "validateHeaderChain", header}, "", 0, `{"total_difficulty":"7019199231177"}`, ""},
+		{"text output", []string{"parseHeader", header}, "", 0, "Time Stamp", ""},
+		{"short header", []string{"parseHeader", "00"}, "", 1, "", "Expected 80 bytes"},
+		{"json error", []string{"-o", "json", "parseVin", "00"}, "", 1, "", `{"error":"Invalid Vin"}`},
+		{"unknown command", []string{"parse"}, "", 2, "", "Unknown command: parse"},
+		{"no command", []string{}, "", 2, "", "Not enough arguments"},
+		{"unknown format", []string{"-o", "yaml", "parseHeader", header}, "", 2, "", "Unknown output format: yaml"},
+		{"unknown network", []string{"-net", "moon", "parseHeader", header}, "", 2, "", ""},
+
+		// Bytestring arguments are hex, @file or - for stdin
+		{"wrapped hex file", []string{"parseHeader", writeFile(t, dir, "wrapped", []byte(wrapped))}, "", 0, "Time Stamp", ""},
+		{"raw file", []string{"parseHeader", writeFile(t, dir, "raw", decodeHex(header))}, "", 0, "Time Stamp", ""},
+		{"stdin", []string{"parseHeader", "-"}, header + "\n", 0, "Time Stamp", ""},
+		{"empty stdin", []string{"validateHeaderChain", "-"}, "", 0, "", ""},
+		{"odd hex file", []string{"parseHeader", writeFile(t, dir, "odd", []byte(header[1:]))}, "", 2, "", "Argument 1: Invalid hex"},
+		{"bad hex argument", []string{"parseHeader", "zz"}, "", 2, "", "Argument 1: Invalid hex"},
+		{"missing file", []string{"parseHeader", "@" + filepath.Join(dir, "missing")}, "", 1, "", "Argument 1:"},
+		{"two stdin arguments", []string{"prove", "-", "-", vout, locktime, strings.Repeat("00", 32), "", "0"}, "", 2, "", "Only one argument may be read from stdin"},
+		{"wrong argument count", []string{"parseHeader"}, "", 2, "", "Usage: spvcli [flags] parseHeader <header>"},
+
+		// prove fails on invalid proofs, still reporting them
+		{"valid prove", []string{"-json", "prove", version, vin, vout, locktime, hex.EncodeToString(txid[:]), "", "0"}, "", 0, `{"valid":true}`, ""},
+		{"invalid prove", []string{"-json", "prove", version, vin, vout, locktime, strings.Repeat("00", 32), "", "0"}, "", 1, `{"valid":false}`, "Merkle Proof is not valid"},
+		{"bad prove index", []string{"prove", version, vin, vout, locktime, strings.Repeat("00", 32), "", "-1"}, "", 2, "", "Invalid index"},
+
+		// verifyProof reports every proof, then fails if any is invalid
+		{"verifyProof", []string{"-json", "verifyProof", "../../testProofs.json"}, "", 1, `"name":"valid[0]","tx_id":"0x5176f6b03b8bc29f4deafbb7384b673debde6ae712deab93f3b0c91fdcd6d674","valid":true`, "4 of 5 proofs are invalid"},
+		{"verifyProof confirmations", []string{"-json", "verifyProof", "-confirmations", "2", "../../testProofs.json"}, "", 1, "Proof has 1 confirmations, expected at least 2", "5 of 5 proofs are invalid"},
+		{"verifyProof @file", []string{"verifyProof", "@../../testProofs.json"}, "", 1, "", "4 of 5 proofs are invalid"},
+		{"verifyProof stdin", []string{"verifyProof", "-"}, "{}", 1, "", ""},
+		{"verifyProof bad document", []string{"verifyProof", "-"}, "[", 1, "", "Invalid proof document"},
+		{"verifyProof missing file", []string{"verifyProof", "missing.json"}, "", 1, "", ""},
+		{"verifyProof bad headers", []string{"verifyProof", "-headers", "zz", "-"}, "", 2, "", "Invalid headers"},
+		{"verifyProof usage", []string{"verifyProof"}, "", 2, "", "Usage: verifyProof"},
+
+		{"buildProof", []string{"-json", "buildProof", "-height", "7", hex.EncodeToString(block), hex.EncodeToString(txidBE[:])}, "", 0, `"height":7`, ""},
+		{"buildProof from stdin", []string{"buildProof", "-", hex.EncodeToString(txidBE[:])}, string(block), 0, "intermediate_nodes", ""},
+		{"buildProof bad txid", []string{"buildProof", hex.EncodeToString(block), "zz"}, "", 2, "", "Invalid txid: encoding/hex"},
+		{"buildProof short txid", []string{"buildProof", hex.EncodeToString(block), "0xabcd"}, "", 2, "", "Invalid txid"},
+		{"buildProof missing tx", []string{"buildProof", hex.EncodeToString(block), strings.Repeat("00", 32)}, "", 1, "", ""},
+		{"buildProof bad block", []string{"buildProof", header, hex.EncodeToString(txidBE[:])}, "", 1, "", ""},
+		{"buildProof usage", []string{"buildProof", header}, "", 2, "", "Usage: buildProof"},
+
+		{"decodeTx", []string{"-json", "decodeTx", version + vin + vout + locktime}, "", 0, `"type":"WSH"`, ""},
+		{"decodeTx text", []string{"decodeTx", version + vin + vout + locktime}, "", 0, "TxID: " + hex.EncodeToString(txidBE[:]), ""},
+		{"decodeTx from stdin", []string{"decodeTx", "-"}, version + vin + vout + locktime + "\n", 0, "Type: WSH", ""},
+		{"decodeTx bad tx", []string{"decodeTx", version}, "", 1, "", ""},
+		{"decodeTx usage", []string{"decodeTx"}, "", 2, "", "decodeTx <tx>"},
+	}
+
+	for _, c := range cases {
+		code, stdout, stderr := runCLI(c.args, c.stdin)
+		assert.Equal(t, c.code, code, c.name)
+		assert.Contains(t, stdout, c.stdout, c.name)
+		assert.Contains(t, stderr, c.stderr, c.name)
+		if c.code == 0 {
+			assert.Equal(t, "", stderr, c.name)
+		}
+	}
+
+	// decodeTx is not limited to the 252 outputs that parseVout accepts
+	outputs := "fdfd00" + strings.Repeat("0000000000000000"+"016a", 253)
+	code, stdout, _ := runCLI([]string{"-json", "decodeTx", version + vin + outputs + locktime}, "")
+	assert.Equal(t, 0, code)
+	var decoded struct {
+		Outputs []OutputInfo `json:"outputs"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &decoded))
+	assert.Len(t, decoded.Outputs, 253)
+	code, _, _ = runCLI([]string{"parseVout", outputs}, "")
+	assert.Equal(t, 1, code)
+}
+
+func TestRunBatch(t *testing.T) {
+	requests := strings.Join([]string{
+		"# headers",
+		"parseHeader " + header,
+		"",
+		"parseHeader 00",
+		"parseHeader - ",
+		"nope",
+		"validateHeaderChain " + header,
+	}, "\n")
+
+	// In JSON mode, each request writes one line to stdout, its result or error
+	code, stdout, stderr := runCLI([]string{"-json", "-batch"}, requests)
+	assert.Equal(t, 1, code)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	assert.Len(t, lines, 5)
+	assert.Contains(t, lines[0], `"digest"`)
+	assert.Equal(t, `{"error":"Expected 80 bytes in a RawHeader got 1"}`, lines[1])
+	assert.Equal(t, `{"error":"Argument 1: Cannot read an argument from stdin in batch mode"}`, lines[2])
+	assert.Equal(t, `{"error":"Unknown command: nope"}`, lines[3])
+	assert.Contains(t, lines[4], `"total_difficulty"`)
+
+	// Errors are also written to stderr with their line numbers
+	errLines := strings.Split(strings.TrimSuffix(stderr, "\n"), "\n")
+	assert.Equal(t, []string{
+		`{"error":"line 4: Expected 80 bytes in a RawHeader got 1"}`,
+		`{"error":"line 5: Argument 1: Cannot read an argument from stdin in batch mode"}`,
+		`{"error":"line 6: Unknown command: nope"}`,
+	}, errLines)
+
+	// Text mode only writes results to stdout
+	code, stdout, stderr = runCLI([]string{"-batch"}, "parseHeader "+header+"\nparseHeader 00")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, 1, strings.Count(stdout, "Time Stamp"))
+	assert.Equal(t, "line 2: Expected 80 bytes in a RawHeader got 1\n", stderr)
+
+	code, _, stderr = runCLI([]string{"-batch"}, "parseHeader "+header+"\n")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stderr)
+
+	code, _, _ = runCLI([]string{"-batch", "parseHeader"}, "")
+	assert.Equal(t, 2, code)
+}
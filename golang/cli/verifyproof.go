@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// ProofReport is the result of verifying one proof
+// Confirmations counts the confirming header and any headers passed with
+// -headers. It is 0 if the proof or headers are invalid.
+type ProofReport struct {
+	Name          string               `json:"name"`
+	TxID          btcspv.Hash256Digest `json:"tx_id"`
+	Valid         bool                 `json:"valid"`
+	Confirmations uint32               `json:"confirmations"`
+	Error         string               `json:"error,omitempty"`
+}
+
+// VerifyProofResult is the result of verifyProof
+type VerifyProofResult struct {
+	Proofs []ProofReport `json:"proofs"`
+}
+
+// Text formats each proof report
+func (r VerifyProofResult) Text() string {
+	var formatted string
+	for i, p := range r.Proofs {
+		formatted += fmt.Sprintf(
+			"\nProof #%d (%s):\n  TxID: %s,\n  Valid: %t,\n  Confirmations: %d\n",
+			i+1, p.Name, hex.EncodeToString(p.TxID[:]), p.Valid, p.Confirmations)
+		if p.Error != "" {
+			formatted += fmt.Sprintf("  Error: %s\n", p.Error)
+		}
+	}
+	return formatted
+}
+
+// namedProof is an undecoded proof, named by its place in the document
+type namedProof struct {
+	name string
+	raw  json.RawMessage
+}
+
+// unquoteProof returns the proof in raw
+// testProofs.json stores valid proofs as JSON strings of JSON objects.
+func unquoteProof(raw json.RawMessage) json.RawMessage {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return json.RawMessage(s)
+	}
+	return raw
+}
+
+// loadProofs splits a JSON document into proofs
+// The document may be a proof, an array of proofs, or laid out like
+// testProofs.json, with "valid" proofs and "badSPVProofs" cases.
+func loadProofs(document []byte) ([]namedProof, error) {
+	document = bytes.TrimSpace(document)
+
+	if len(document) > 0 && document[0] == '[' {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(document, &raws); err != nil {
+			return nil, err
+		}
+		proofs := []namedProof{}
+		for i := range raws {
+			proofs = append(proofs, namedProof{fmt.Sprintf("proofs[%d]", i), unquoteProof(raws[i])})
+		}
+		return proofs, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(document, &fields); err != nil {
+		return nil, err
+	}
+	_, hasValid := fields["valid"]
+	_, hasBad := fields["badSPVProofs"]
+	if !hasValid && !hasBad {
+		return []namedProof{{"proof", document}}, nil
+	}
+
+	var layout struct {
+		Valid []json.RawMessage `json:"valid"`
+		Bad   []struct {
+			Comment string          `json:"comment"`
+			Proof   json.RawMessage `json:"proof"`
+		} `json:"badSPVProofs"`
+	}
+	if err := json.Unmarshal(document, &layout); err != nil {
+		return nil, err
+	}
+	proofs := []namedProof{}
+	for i := range layout.Valid {
+		proofs = append(proofs, namedProof{fmt.Sprintf("valid[%d]", i), unquoteProof(layout.Valid[i])})
+	}
+	for i, c := range layout.Bad {
+		proofs = append(proofs, namedProof{fmt.Sprintf("badSPVProofs[%d] %s", i, c.Comment), unquoteProof(c.Proof)})
+	}
+	return proofs, nil
+}
+
+// verifyProof validates a proof, and the headers confirming it
+func verifyProof(proof btcspv.SPVProof, headers []byte, minConfirmations uint32, net *btcspv.NetParams) (uint32, error) {
+	if _, err := proof.Validate(); err != nil {
+		return 0, err
+	}
+
+	// Validate does not check the confirming header's work, so include it
+	chain := append(proof.ConfirmingHeader.Raw[:], headers...)
+	if _, err := btcspv.ValidateHeaderChain(chain, net); err != nil {
+		return 0, err
+	}
+
+	confirmations := uint32(len(chain) / 80)
+	if confirmations < minConfirmations {
+		return confirmations, btcspv.NewSPVError(
+			btcspv.ErrCodeInsufficientConfirmations,
+			fmt.Sprintf("Proof has %d confirmations, expected at least %d", confirmations, minConfirmations))
+	}
+	return confirmations, nil
+}
+
+// VerifyProof verifies the SPVProofs in a JSON file, or stdin if the file is -
+// Usage: verifyProof [-headers hex] [-confirmations n] <file|@file|->
+// headers is a chain of headers built on each proof's confirming header. The
+// result reports every proof, and the error is set if any is invalid.
+func VerifyProof(args []string, net *btcspv.NetParams, stdin io.Reader) (Result, error) {
+	flags := flag.NewFlagSet("verifyProof", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	headersHex := flags.String("headers", "", "headers built on the confirming header, as hex or @file")
+	minConfirmations := flags.Uint("confirmations", 0, "minimum confirmations, counting the confirming header")
+	if err := flags.Parse(args); err != nil {
+		return nil, usageError{fmt.Sprintf("verifyProof: %s", err)}
+	}
+	if flags.NArg() != 1 {
+		return nil, usageError{"Usage: verifyProof [-headers hex] [-confirmations n] <file|@file|->"}
+	}
+
+	headers, err := readArg(*headersHex, stdin)
+	if err != nil {
+		return nil, prefixError("Invalid headers", err)
+	}
+
+	// A bare path is read as a file, like @path
+	document, ok, err := readSource(flags.Arg(0), stdin)
+	if err == nil && !ok {
+		document, err = ioutil.ReadFile(flags.Arg(0))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	proofs, err := loadProofs(document)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid proof document: %s", err)
+	}
+	if len(proofs) == 0 {
+		return nil, errors.New("No proofs found")
+	}
+
+	result := VerifyProofResult{Proofs: []ProofReport{}}
+	invalid := 0
+	for _, p := range proofs {
+		report := ProofReport{Name: p.name}
+
+		var proof btcspv.SPVProof
+		err := json.Unmarshal(p.raw, &proof)
+		if err == nil {
+			report.TxID = proof.TxID
+			report.Confirmations, err = verifyProof(proof, headers, uint32(*minConfirmations), net)
+		}
+		if err != nil {
+			report.Error = err.Error()
+			invalid++
+		} else {
+			report.Valid = true
+		}
+		result.Proofs = append(result.Proofs, report)
+	}
+
+	if invalid != 0 {
+		return result, fmt.Errorf("%d of %d proofs are invalid", invalid, len(proofs))
+	}
+	return result, nil
+}
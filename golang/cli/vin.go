@@ -1,110 +1,120 @@
 package main
 
 import (
-	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 
 	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
 )
 
-// InputType an enum of types of bitcoin inputs
-type InputType int
+// InputInfo is human-readable information about an input
+// NestedType is the nested witness program of compatibility inputs.
+type InputInfo struct {
+	Outpoint   btcspv.Hash256Digest `json:"outpoint"`
+	Index      uint                 `json:"index"`
+	Type       string               `json:"type"`
+	NestedType string               `json:"nested_type,omitempty"`
+	Sequence   uint                 `json:"sequence"`
+}
 
-// possible input types
-const (
-	InputNone     InputType = 0
-	Legacy        InputType = 1
-	Compatibility InputType = 2
-	Witness       InputType = 3
-)
+// VinResult is the result of parseVin
+type VinResult struct {
+	Inputs []InputInfo `json:"inputs"`
+}
 
-func prettifyInput(numInput int, outpoint btcspv.Hash256Digest, index uint, inputType InputType, sequence uint) string {
-	outpointStr := hex.EncodeToString(outpoint[:])
+// Text formats each input
+func (r VinResult) Text() string {
+	var formattedInputs string
+	for i := range r.Inputs {
+		formattedInputs += prettifyInput(i+1, r.Inputs[i])
+	}
+	return formattedInputs
+}
 
-	// Get the input type in readable format
-	inputTypeString := GetInputType(inputType)
+func prettifyInput(numInput int, input InputInfo) string {
+	outpointStr := hex.EncodeToString(input.Outpoint[:])
 
-	dataStr := fmt.Sprintf("\nInput #%d:\n  Outpoint: %s,\n  Index: %d,\n  Type: %s,\n  Sequence: %d\n", numInput, outpointStr, index, inputTypeString, sequence)
+	// Name compatibility inputs by their nested witness program, e.g. P2SH-WPKH
+	inputTypeString := input.Type
+	if input.NestedType != "" {
+		inputTypeString = fmt.Sprintf("%s (P2SH-%s)", input.Type, input.NestedType)
+	}
+
+	dataStr := fmt.Sprintf("\nInput #%d:\n  Outpoint: %s,\n  Index: %d,\n  Type: %s,\n  Sequence: %d\n", numInput, outpointStr, input.Index, inputTypeString, input.Sequence)
 
 	return dataStr
 }
 
 // ParseVin parses an input vector from hex
-func ParseVin(vin []byte) string {
+func ParseVin(vin []byte) (VinResult, error) {
 	// Validate the vin
 	isVin := btcspv.ValidateVin(vin)
 	if !isVin {
-		return "Invalid Vin\n"
+		return VinResult{}, errors.New("Invalid Vin")
 	}
 
-	numInputs := int(vin[0])
-	var formattedInputs string
-	for i := 0; i < numInputs; i++ {
+	_, numInputs, _ := btcspv.ParseVarInt(vin)
+	result := VinResult{Inputs: []InputInfo{}}
+	for i := uint64(0); i < numInputs; i++ {
 		// Extract each vin at the specified index
-		vin, _ := btcspv.ExtractInputAtIndex(vin, uint(i))
+		input, _ := btcspv.ExtractInputAtIndex(vin, uint(i))
 
 		// Use ParseInput to get more information about the vin
-		sequence, inputID, inputIndex, inputType := parseInput(vin)
-
-		// Format information about the vin
-		numInput := i + 1
-		data := prettifyInput(numInput, inputID, inputIndex, inputType, sequence)
-
-		// Concat vin information onto `formattedInputs`
-		formattedInputs = formattedInputs + data
+		info, err := parseInput(input)
+		if err != nil {
+			return VinResult{}, err
+		}
+		result.Inputs = append(result.Inputs, info)
 	}
 
-	return formattedInputs
+	return result, nil
 }
 
 // ExtractInputTxID returns the input tx id bytes
-func ExtractInputTxID(input []byte) btcspv.Hash256Digest {
-	LE := btcspv.ExtractInputTxIDLE(input)
+func ExtractInputTxID(input []byte) (btcspv.Hash256Digest, error) {
+	LE, err := btcspv.ExtractInputTxIDLE(input)
+	if err != nil {
+		return btcspv.Hash256Digest{}, err
+	}
 	txID := btcspv.ReverseHash256Endianness(LE)
-	return txID
+	return txID, nil
 }
 
 // ParseInput returns human-readable information about an input
-func parseInput(input []byte) (uint, btcspv.Hash256Digest, uint, InputType) {
-	// NB: If the scriptsig is exactly 00, we are Witness.
-	// Otherwise we are Compatibility or Legacy
-	var sequence uint32
-	var witnessTag []byte
-	var inputType InputType
-
-	if input[36] != 0 {
-		sequence, _ = btcspv.ExtractSequenceLegacy(input)
-		witnessTag = input[36:39:39]
+func parseInput(input []byte) (InputInfo, error) {
+	inputType, nestedType, err := btcspv.ClassifyInput(input)
+	if err != nil {
+		return InputInfo{}, err
+	}
 
-		if bytes.Equal(witnessTag, []byte{34, 0, 32}) || bytes.Equal(witnessTag, []byte{22, 0, 20}) {
-			inputType = Compatibility
-		} else {
-			inputType = Legacy
-		}
+	var sequence uint32
+	if inputType == btcspv.InputWitness {
+		sequence, err = btcspv.ExtractSequenceWitness(input)
 	} else {
-		sequence = btcspv.ExtractSequenceWitness(input)
-		inputType = Witness
+		sequence, err = btcspv.ExtractSequenceLegacy(input)
+	}
+	if err != nil {
+		return InputInfo{}, err
 	}
 
-	inputID := ExtractInputTxID(input)
-	inputIndex := btcspv.ExtractTxIndex(input)
-
-	return uint(sequence), inputID, inputIndex, inputType
-}
+	inputID, err := ExtractInputTxID(input)
+	if err != nil {
+		return InputInfo{}, err
+	}
+	inputIndex, err := btcspv.ExtractTxIndex(input)
+	if err != nil {
+		return InputInfo{}, err
+	}
 
-// GetInputType returns the name of the input type associated with the number
-func GetInputType(inputType InputType) string {
-	var typeString string
-	switch inputType {
-	case InputNone:
-		typeString = "Input None"
-	case Legacy:
-		typeString = "Legacy"
-	case Compatibility:
-		typeString = "Compatibility"
-	case Witness:
-		typeString = "Witness"
+	info := InputInfo{
+		Outpoint: inputID,
+		Index:    inputIndex,
+		Type:     inputType.String(),
+		Sequence: uint(sequence),
+	}
+	if inputType == btcspv.InputCompatibility {
+		info.NestedType = nestedType.String()
 	}
-	return typeString
+	return info, nil
 }
@@ -1,153 +1,138 @@
 package main
 
 import (
-	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 
 	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
 )
 
-// OutputType an enum of types of bitcoin outputs
-type OutputType int
-
-// possible output types
-const (
-	OutputNone  OutputType = 0
-	WPKH        OutputType = 1
-	WSH         OutputType = 2
-	OpReturn    OutputType = 3
-	PKH         OutputType = 4
-	SH          OutputType = 5
-	Nonstandard OutputType = 6
-)
-
-func prettifyOutput(
-	numOutput int,
-	outpoint []byte,
-	value uint,
-	outputType OutputType) string {
+// OutputInfo is human-readable information about an output
+// Pushes holds each element pushed by an OP_RETURN output.
+type OutputInfo struct {
+	Address string            `json:"address"`
+	Payload btcspv.HexBytes   `json:"payload"`
+	Pushes  []btcspv.HexBytes `json:"pushes,omitempty"`
+	Value   uint              `json:"value"`
+	Type    string            `json:"type"`
+}
 
-	outpointStr := hex.EncodeToString(outpoint)
+// VoutResult is the result of parseVout
+type VoutResult struct {
+	Outputs []OutputInfo `json:"outputs"`
+}
 
-	// Get the output type in readable format
-	outputTypeString := GetOutputType(outputType)
+// Text formats each output
+func (r VoutResult) Text() string {
+	var formattedOutputs string
+	for i := range r.Outputs {
+		if r.Outputs[i].Pushes != nil {
+			formattedOutputs += prettifyOpReturn(i+1, r.Outputs[i])
+		} else {
+			formattedOutputs += prettifyOutput(i+1, r.Outputs[i])
+		}
+	}
+	return formattedOutputs
+}
 
-	// Get the address associated with the output
-	address := getAddress(outputType, outpoint)
+func prettifyOutput(numOutput int, output OutputInfo) string {
+	payloadStr := hex.EncodeToString(output.Payload)
 
 	dataStr := fmt.Sprintf(
 		"\nOutput #%d:\n  Address: %s\n  Payload: %s,\n  Value: %d,\n  Type: %s\n",
-		numOutput, address, outpointStr, value, outputTypeString)
+		numOutput, output.Address, payloadStr, output.Value, output.Type)
 	return dataStr
 }
 
-// getAddress return the address associated with the output
-func getAddress(outputType OutputType, outpoint []byte) string {
-	var address string
-	var err error
+// prettifyOpReturn formats an op return output, showing each pushed element
+func prettifyOpReturn(numOutput int, output OutputInfo) string {
+	pushesStr := fmt.Sprintf(" %d\n", len(output.Pushes))
+	for i := range output.Pushes {
+		pushesStr += fmt.Sprintf("    #%d: %s,\n", i+1, hex.EncodeToString(output.Pushes[i]))
+	}
 
+	dataStr := fmt.Sprintf(
+		"\nOutput #%d:\n  Pushes:%s  Value: %d,\n  Type: %s\n",
+		numOutput, pushesStr, output.Value, output.Type)
+	return dataStr
+}
+
+// getAddress return the address associated with the output
+func getAddress(outputType btcspv.OutputType, payload []byte, net *btcspv.NetParams) (string, error) {
 	switch outputType {
-	case WPKH:
-		address, err = btcspv.EncodeP2WPKH(outpoint)
-	case WSH:
-		digest, _ := btcspv.NewHash256Digest(outpoint)
-		address, err = btcspv.EncodeP2WSH(digest)
-	case PKH:
-		address, err = btcspv.EncodeP2PKH(outpoint)
-	case SH:
-		address, err = btcspv.EncodeP2SH(outpoint)
+	case btcspv.OutputWPKH:
+		return btcspv.EncodeP2WPKH(payload, net)
+	case btcspv.OutputWSH:
+		digest, _ := btcspv.NewHash256Digest(payload)
+		return btcspv.EncodeP2WSH(digest, net)
+	case btcspv.OutputPKH:
+		return btcspv.EncodeP2PKH(payload, net)
+	case btcspv.OutputSH:
+		return btcspv.EncodeP2SH(payload, net)
+	case btcspv.OutputTR:
+		return btcspv.EncodeP2TR(payload, net)
 	default:
-		address = ""
-	}
-
-	if err != nil {
-		return fmt.Sprintf("%s\n", err)
+		return "", nil
 	}
-	return address
 }
 
 // ParseVout parses an output vector from hex
-func ParseVout(vout []byte) string {
+// Addresses are encoded for net
+func ParseVout(vout []byte, net *btcspv.NetParams) (VoutResult, error) {
 	// Validate the vout
 	isVout := btcspv.ValidateVout(vout)
 	if !isVout {
-		return "Invalid Vout\n"
+		return VoutResult{}, errors.New("Invalid Vout")
 	}
 
-	numOutputs := int(vout[0])
-	var formattedOutputs string
-	for i := 0; i < numOutputs; i++ {
+	_, numOutputs, _ := btcspv.ParseVarInt(vout)
+	result := VoutResult{Outputs: []OutputInfo{}}
+	for i := uint64(0); i < numOutputs; i++ {
 		// Extract each vout at the specified index
-		vout, err := btcspv.ExtractOutputAtIndex(vout, uint(i))
+		output, err := btcspv.ExtractOutputAtIndex(vout, uint(i))
 		if err != nil {
-			return fmt.Sprintf("%s\n", err)
+			return VoutResult{}, err
 		}
 
-		// Use ParseOutput to get more information about the vout
-		value, outputType, payload := parseOutput(vout)
-
-		// Format information about the vout
-		numOutput := i + 1
-		data := prettifyOutput(numOutput, payload, value, outputType)
-
-		// Concat vout information onto formattedOutputs
-		formattedOutputs = formattedOutputs + data
+		info, err := parseOutput(output, net)
+		if err != nil {
+			return VoutResult{}, err
+		}
+		result.Outputs = append(result.Outputs, info)
 	}
 
-	return formattedOutputs
+	return result, nil
 }
 
-// ParseOutput extracts human-readable information from an output
-func parseOutput(output []byte) (uint, OutputType, []byte) {
-	value := btcspv.ExtractValue(output)
-	var outputType OutputType
-	var payload []byte
-
-	if output[9] == 0x6a {
-		outputType = OpReturn
-		payload, _ = btcspv.ExtractOpReturnData(output)
-	} else {
-		prefixHash := output[8:10:10]
-		if bytes.Equal(prefixHash, []byte{0x22, 0x00}) {
-			outputType = WSH
-			payload = output[11:43:43]
-		} else if bytes.Equal(prefixHash, []byte{0x16, 0x00}) {
-			outputType = WPKH
-			payload = output[11:31:31]
-		} else if bytes.Equal(prefixHash, []byte{0x19, 0x76}) {
-			outputType = PKH
-			payload = output[12:32:32]
-		} else if bytes.Equal(prefixHash, []byte{0x17, 0xa9}) {
-			outputType = SH
-			payload = output[11:31:31]
-		} else {
-			outputType = Nonstandard
-			payload = []byte{}
-		}
+// parseOutput returns human-readable information about an output
+func parseOutput(output []byte, net *btcspv.NetParams) (OutputInfo, error) {
+	value, err := btcspv.ExtractValue(output)
+	if err != nil {
+		return OutputInfo{}, err
+	}
+	outputType, payload, err := btcspv.ClassifyOutput(output)
+	if err != nil {
+		return OutputInfo{}, err
+	}
+	address, err := getAddress(outputType, payload, net)
+	if err != nil {
+		return OutputInfo{}, err
 	}
 
-	return value, outputType, payload
-}
-
-// GetOutputType returns the name of the output type associated with the number
-func GetOutputType(outputType OutputType) string {
-	var typeString string
-	switch outputType {
-	case OutputNone:
-		typeString = "Output None"
-	case WPKH:
-		typeString = "WPKH"
-	case WSH:
-		typeString = "WSH"
-	case OpReturn:
-		typeString = "Op Return"
-	case PKH:
-		typeString = "PKH"
-	case SH:
-		typeString = "SH"
-	case Nonstandard:
-		typeString = "Nonstandard"
+	info := OutputInfo{
+		Address: address,
+		Payload: payload,
+		Value:   value,
+		Type:    outputType.String(),
+	}
+	if outputType == btcspv.OutputOpReturn {
+		// ClassifyOutput has already checked that the output only pushes data
+		pushes, _ := btcspv.ExtractOpReturnPushes(output)
+		info.Pushes = []btcspv.HexBytes{}
+		for i := range pushes {
+			info.Pushes = append(info.Pushes, pushes[i])
+		}
 	}
-	return typeString
+	return info, nil
 }
@@ -1,8 +1,9 @@
 module github.com/summa-tx/bitcoin-spv/golang
 
-go 1.12
+go 1.13
 
 require (
+	github.com/btcsuite/btcd v0.0.0-20190115013929-ed77733ec07d
 	github.com/btcsuite/btcutil v0.0.0-20180706230648-ab6388e0c60a
 	github.com/cosmos/cosmos-sdk v0.35.0
 	github.com/gogo/protobuf v1.1.1
@@ -0,0 +1,17 @@
+package relay
+
+import "errors"
+
+// Errors returned by Relay and FileStore. Messages match the require strings of the
+// on-chain relay where one exists.
+var (
+	ErrUnknownHeader         = errors.New("Header is unknown")
+	ErrNotBestKnown          = errors.New("Passed in best is not best known")
+	ErrNotMostRecentAncestor = errors.New("Ancestor must be heaviest common ancestor")
+	ErrNotHeavier            = errors.New("New best hash does not have more work than previous")
+	ErrBelowAncestor         = errors.New("A descendant height is below the ancestor height")
+	ErrHeightOutOfRange      = errors.New("Height out of range")
+	ErrNotLinked             = errors.New("Header does not extend the tip")
+	ErrEmptyStore            = errors.New("Store is empty")
+	ErrCorruptRecord         = errors.New("Record checksum mismatch")
+)
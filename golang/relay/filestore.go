@@ -0,0 +1,293 @@
+package relay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// A record is an 80-byte raw header, a 4-byte LE height, the 32-byte BE
+// chainwork and a 4-byte checksum: the first 4 bytes of the hash256 of the
+// preceding 116 bytes.
+const (
+	recordSize   = 120
+	checksumFrom = 116
+)
+
+// storeFile is the file under a FileStore, an *os.File outside of tests
+type storeFile interface {
+	io.ReadWriteCloser
+	io.ReaderAt
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+}
+
+// FileStore persists a linear header chain as fixed-size records appended to
+// a single file. Only digests are held in memory. Records after a torn or
+// otherwise invalid record are discarded when the file is opened. A FileStore
+// is safe for concurrent use.
+type FileStore struct {
+	mu sync.RWMutex
+
+	file       storeFile
+	baseHeight uint32
+	digests    []Hash256Digest
+	heights    map[Hash256Digest]uint32
+}
+
+// encodeRecord serializes a header, its height and its chainwork
+func encodeRecord(header btcspv.BitcoinHeader, chainwork btcspv.Uint256) []byte {
+	record := make([]byte, recordSize)
+	copy(record[0:80], header.Raw[:])
+	binary.LittleEndian.PutUint32(record[80:84], header.Height)
+	work := chainwork.Bytes()
+	copy(record[84:116], work[:])
+	sum := btcspv.Hash256(record[:checksumFrom])
+	copy(record[checksumFrom:], sum[:4])
+	return record
+}
+
+// decodeRecord deserializes a record, and reports whether its checksum is valid
+func decodeRecord(record []byte) (StoredHeader, bool) {
+	sum := btcspv.Hash256(record[:checksumFrom])
+	if !bytes.Equal(sum[:4], record[checksumFrom:recordSize]) {
+		return StoredHeader{}, false
+	}
+
+	raw, _ := btcspv.NewRawHeader(record[0:80])
+	height := binary.LittleEndian.Uint32(record[80:84])
+	chainwork, _ := btcspv.Uint256FromBytes(record[84:116])
+
+	return StoredHeader{btcspv.HeaderFromRaw(raw, height), chainwork}, true
+}
+
+// OpenFileStore opens or creates a FileStore at path and rebuilds its index
+// Every record must have a valid checksum and extend the record before it.
+// The file is truncated at the first record that does not.
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return newFileStore(file)
+}
+
+// newFileStore rebuilds the index of a FileStore over an open file
+func newFileStore(file storeFile) (*FileStore, error) {
+	s := &FileStore{
+		file:    file,
+		heights: map[Hash256Digest]uint32{},
+	}
+
+	reader := bufio.NewReader(file)
+	record := make([]byte, recordSize)
+	var prev StoredHeader
+
+	for {
+		if _, err := io.ReadFull(reader, record); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			file.Close()
+			return nil, err
+		}
+
+		stored, ok := decodeRecord(record)
+		if !ok {
+			break
+		}
+		if len(s.digests) == 0 {
+			s.baseHeight = stored.Header.Height
+		} else if !extends(prev, stored) {
+			break
+		}
+
+		s.digests = append(s.digests, stored.Header.Hash)
+		s.heights[stored.Header.Hash] = stored.Header.Height
+		prev = stored
+	}
+
+	// Drop a torn final write, and anything after an invalid record
+	if err := s.truncate(len(s.digests)); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// extends reports whether next is the child of prev, one height above it
+func extends(prev, next StoredHeader) bool {
+	return next.Header.Height == prev.Header.Height+1 &&
+		next.Header.PrevHash == prev.Header.Hash
+}
+
+// truncate cuts the file to count records and syncs it
+func (s *FileStore) truncate(count int) error {
+	if err := s.file.Truncate(int64(count) * recordSize); err != nil {
+		return err
+	}
+	if _, err := s.file.Seek(int64(count)*recordSize, io.SeekStart); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// discardAppend truncates the records of a failed append
+// It returns the append's error, wrapped with the truncation's error if the
+// file may still hold part of the append.
+func (s *FileStore) discardAppend(err error) error {
+	if truncErr := s.truncate(len(s.digests)); truncErr != nil {
+		return fmt.Errorf("%w; discarding the partial append also failed, the file may hold orphaned records: %v", err, truncErr)
+	}
+	return err
+}
+
+// Close closes the underlying file
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// Len returns the number of stored headers
+func (s *FileStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.digests)
+}
+
+// Append stores headers extending the tip, in one write followed by a sync
+// The first header in an empty store may be at any height. The header's Hash
+// and PrevHash are recomputed from Raw. The caller is responsible for
+// validating work and difficulty, e.g. with a Relay.
+func (s *FileStore) Append(headers ...StoredHeader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(headers) == 0 {
+		return nil
+	}
+
+	var prev StoredHeader
+	if len(s.digests) != 0 {
+		tip, err := s.read(len(s.digests) - 1)
+		if err != nil {
+			return err
+		}
+		prev = tip
+	}
+
+	buf := make([]byte, 0, len(headers)*recordSize)
+	for i, h := range headers {
+		next := StoredHeader{btcspv.HeaderFromRaw(h.Header.Raw, h.Header.Height), h.Chainwork}
+		if (len(s.digests) != 0 || i != 0) && !extends(prev, next) {
+			return ErrNotLinked
+		}
+		buf = append(buf, encodeRecord(next.Header, next.Chainwork)...)
+		prev = next
+	}
+
+	// On failure, leave the file as the index describes it, so the next
+	// append is not written after orphaned records
+	if _, err := s.file.Write(buf); err != nil {
+		return s.discardAppend(err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return s.discardAppend(err)
+	}
+
+	if len(s.digests) == 0 {
+		s.baseHeight = headers[0].Header.Height
+	}
+	for _, h := range headers {
+		digest := btcspv.Hash256(h.Header.Raw[:])
+		s.digests = append(s.digests, digest)
+		s.heights[digest] = h.Header.Height
+	}
+	return nil
+}
+
+// Rollback discards every header above height
+// Rolling back to a height at or above the tip does nothing. Rolling back
+// below the first stored header empties the store.
+func (s *FileStore) Rollback(height uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.digests) == 0 || height >= s.baseHeight+uint32(len(s.digests))-1 {
+		return nil
+	}
+
+	keep := 0
+	if height >= s.baseHeight {
+		keep = int(height-s.baseHeight) + 1
+	}
+
+	if err := s.truncate(keep); err != nil {
+		return err
+	}
+
+	for _, digest := range s.digests[keep:] {
+		delete(s.heights, digest)
+	}
+	s.digests = s.digests[:keep]
+	return nil
+}
+
+// read reads the record at an index into the file
+func (s *FileStore) read(index int) (StoredHeader, error) {
+	record := make([]byte, recordSize)
+	if _, err := s.file.ReadAt(record, int64(index)*recordSize); err != nil {
+		return StoredHeader{}, err
+	}
+
+	stored, ok := decodeRecord(record)
+	if !ok {
+		return StoredHeader{}, ErrCorruptRecord
+	}
+	return stored, nil
+}
+
+// Tip returns the highest stored header
+func (s *FileStore) Tip() (StoredHeader, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.digests) == 0 {
+		return StoredHeader{}, ErrEmptyStore
+	}
+	return s.read(len(s.digests) - 1)
+}
+
+// HeaderAt returns the stored header at a height
+func (s *FileStore) HeaderAt(height uint32) (StoredHeader, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if height < s.baseHeight || uint64(height-s.baseHeight) >= uint64(len(s.digests)) {
+		return StoredHeader{}, ErrHeightOutOfRange
+	}
+	return s.read(int(height - s.baseHeight))
+}
+
+// Height returns the height of a stored header
+func (s *FileStore) Height(digest Hash256Digest) (uint32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	height, ok := s.heights[digest]
+	if !ok {
+		return 0, ErrUnknownHeader
+	}
+	return height, nil
+}
@@ -0,0 +1,112 @@
+package relay
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+var (
+	errSync     = errors.New("sync failed")
+	errTruncate = errors.New("truncate failed")
+)
+
+// failingFile is an *os.File whose Sync and Truncate fail while failSync and
+// failTruncate are set
+type failingFile struct {
+	*os.File
+	failSync     bool
+	failTruncate bool
+}
+
+func (f *failingFile) Sync() error {
+	if f.failSync {
+		return errSync
+	}
+	return f.File.Sync()
+}
+
+func (f *failingFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return errTruncate
+	}
+	return f.File.Truncate(size)
+}
+
+// linkedHeaders builds n headers on prev, starting at height
+// FileStore does not check work, so the headers are not mined.
+func linkedHeaders(prev btcspv.Hash256Digest, height uint32, n int, seed byte) []StoredHeader {
+	headers := []StoredHeader{}
+	for i := 0; i < n; i++ {
+		var raw btcspv.RawHeader
+		copy(raw[4:36], prev[:])
+		raw[36] = seed
+		header := btcspv.HeaderFromRaw(raw, height+uint32(i))
+		headers = append(headers, StoredHeader{header, btcspv.NewUint256(uint64(i + 1))})
+		prev = header.Hash
+	}
+	return headers
+}
+
+func TestFileStoreSyncFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := dir + "/headers.dat"
+
+	osFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	assert.Nil(t, err)
+	file := &failingFile{File: osFile}
+	s, err := newFileStore(file)
+	assert.Nil(t, err)
+
+	headers := linkedHeaders(btcspv.Hash256Digest{1}, 100, 3, 0)
+	assert.Nil(t, s.Append(headers[0]))
+
+	// A failed sync must not leave records the index does not describe
+	file.failSync = true
+	err = s.Append(headers[1:]...)
+	assert.True(t, errors.Is(err, errSync))
+	assert.Equal(t, 1, s.Len())
+	file.failSync = false
+
+	fork := linkedHeaders(headers[0].Header.Hash, 101, 2, 1)
+	assert.Nil(t, s.Append(fork...))
+	assert.Nil(t, s.Close())
+
+	reopened, err := OpenFileStore(path)
+	assert.Nil(t, err)
+	defer reopened.Close()
+	assert.Equal(t, 3, reopened.Len())
+	tip, err := reopened.Tip()
+	assert.Nil(t, err)
+	assert.Equal(t, fork[1].Header.Hash, tip.Header.Hash)
+}
+
+func TestFileStoreTruncateFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	osFile, err := os.OpenFile(dir+"/headers.dat", os.O_RDWR|os.O_CREATE, 0644)
+	assert.Nil(t, err)
+	file := &failingFile{File: osFile}
+	s, err := newFileStore(file)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	headers := linkedHeaders(btcspv.Hash256Digest{1}, 100, 2, 0)
+	assert.Nil(t, s.Append(headers[0]))
+
+	// The caller is told that the partial append may remain
+	file.failSync = true
+	file.failTruncate = true
+	err = s.Append(headers[1])
+	assert.True(t, errors.Is(err, errSync))
+	assert.Contains(t, err.Error(), "truncate failed")
+	assert.Equal(t, 1, s.Len())
+}
@@ -0,0 +1,154 @@
+package relay_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+	"github.com/summa-tx/bitcoin-spv/golang/relay"
+)
+
+// storeFromRelay persists the relay's best chain above height, replacing
+// anything the store holds above it
+func storeFromRelay(s *relay.FileStore, r *relay.Relay, height uint32) error {
+	if err := s.Rollback(height); err != nil {
+		return err
+	}
+
+	headers := []relay.StoredHeader{}
+	for h := height + 1; h <= r.Tip().Height; h++ {
+		header, err := r.HeaderAt(h)
+		if err != nil {
+			return err
+		}
+		work, err := r.Chainwork(header.Hash)
+		if err != nil {
+			return err
+		}
+		headers = append(headers, relay.StoredHeader{Header: header, Chainwork: work})
+	}
+	return s.Append(headers...)
+}
+
+func (suite *RelaySuite) openStore() (*relay.FileStore, string) {
+	dir, err := ioutil.TempDir("", "relay")
+	suite.Nil(err)
+	path := filepath.Join(dir, "headers.dat")
+
+	s, err := relay.OpenFileStore(path)
+	suite.Nil(err)
+	return s, path
+}
+
+func (suite *RelaySuite) TestFileStore() {
+	r := suite.Relay
+	g := suite.Genesis.Hash
+	s, path := suite.openStore()
+	defer os.RemoveAll(filepath.Dir(path))
+
+	_, err := s.Tip()
+	suite.Equal(relay.ErrEmptyStore, err)
+
+	headers, digests := mineChain(g, 5, 1)
+	suite.Nil(r.AddHeaders(g, headers))
+	suite.Nil(r.MarkNewHeaviest(g, g, digests[4], 10))
+
+	genesisWork, _ := r.Chainwork(g)
+	suite.Nil(s.Append(relay.StoredHeader{Header: suite.Genesis, Chainwork: genesisWork}))
+	suite.Nil(storeFromRelay(s, r, genesisHeight))
+	suite.Equal(6, s.Len())
+
+	// Reorg onto a longer fork
+	fork, forkDigests := mineChain(digests[1], 4, 2)
+	suite.Nil(r.AddHeaders(digests[1], fork))
+	suite.Nil(r.MarkNewHeaviest(digests[1], digests[4], forkDigests[3], 10))
+	suite.Nil(storeFromRelay(s, r, genesisHeight+2))
+	suite.Equal(7, s.Len())
+
+	_, err = s.Height(digests[4])
+	suite.Equal(relay.ErrUnknownHeader, err)
+	suite.Nil(s.Close())
+
+	// Reopening rebuilds the index
+	s, err = relay.OpenFileStore(path)
+	suite.Nil(err)
+	defer s.Close()
+
+	suite.Equal(7, s.Len())
+	tip, err := s.Tip()
+	suite.Nil(err)
+	suite.Equal(r.Tip(), tip.Header)
+	suite.Equal(btcspv.NewUint256(14), tip.Chainwork)
+
+	stored, err := s.HeaderAt(genesisHeight + 3)
+	suite.Nil(err)
+	suite.Equal(forkDigests[0], stored.Header.Hash)
+
+	height, err := s.Height(forkDigests[2])
+	suite.Nil(err)
+	suite.Equal(uint32(genesisHeight+5), height)
+
+	_, err = s.HeaderAt(genesisHeight - 1)
+	suite.Equal(relay.ErrHeightOutOfRange, err)
+	_, err = s.HeaderAt(genesisHeight + 7)
+	suite.Equal(relay.ErrHeightOutOfRange, err)
+
+	// Appends must extend the tip
+	suite.Equal(relay.ErrNotLinked, s.Append(tip))
+	stale, _ := r.Header(digests[4])
+	suite.Equal(relay.ErrNotLinked, s.Append(relay.StoredHeader{Header: stale}))
+
+	// Rolling back below the first header empties the store
+	suite.Nil(s.Rollback(genesisHeight + 10))
+	suite.Equal(7, s.Len())
+	suite.Nil(s.Rollback(genesisHeight - 1))
+	suite.Equal(0, s.Len())
+}
+
+func (suite *RelaySuite) TestFileStoreTornWrite() {
+	headers, _ := mineChain(suite.Genesis.Hash, 3, 1)
+	s, path := suite.openStore()
+	defer os.RemoveAll(filepath.Dir(path))
+
+	stored := []relay.StoredHeader{{Header: suite.Genesis}}
+	for i := 0; i < 3; i++ {
+		raw, _ := btcspv.NewRawHeader(headers[i*80 : i*80+80])
+		stored = append(stored, relay.StoredHeader{Header: btcspv.HeaderFromRaw(raw, genesisHeight+uint32(i)+1)})
+	}
+	suite.Nil(s.Append(stored...))
+	suite.Nil(s.Close())
+
+	info, err := os.Stat(path)
+	suite.Nil(err)
+	suite.Equal(int64(4*120), info.Size())
+
+	// A partial final record is dropped
+	suite.Nil(os.Truncate(path, 4*120-7))
+	s, err = relay.OpenFileStore(path)
+	suite.Nil(err)
+	suite.Equal(3, s.Len())
+	tip, err := s.Tip()
+	suite.Nil(err)
+	suite.Equal(stored[2].Header, tip.Header)
+
+	// A complete record can be appended after recovery
+	suite.Nil(s.Append(stored[3]))
+	suite.Nil(s.Close())
+
+	// A record with a bad checksum is dropped, with everything after it
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	suite.Nil(err)
+	_, err = file.WriteAt([]byte{0xff}, 2*120+100)
+	suite.Nil(err)
+	suite.Nil(file.Close())
+
+	s, err = relay.OpenFileStore(path)
+	suite.Nil(err)
+	defer s.Close()
+	suite.Equal(2, s.Len())
+
+	info, err = os.Stat(path)
+	suite.Nil(err)
+	suite.Equal(int64(2*120), info.Size())
+}
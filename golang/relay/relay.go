@@ -0,0 +1,381 @@
+// Package relay is an in-memory Bitcoin header store with fork choice
+// It mirrors the addHeaders/markNewHeaviest semantics of Summa's on-chain
+// relays, so that an off-chain mirror and the contract agree on the best chain.
+package relay
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+)
+
+// Hash256Digest is a 32-byte double-sha2 hash
+type Hash256Digest = btcspv.Hash256Digest
+
+// StoredHeader is a header and the work accumulated up to and including it
+type StoredHeader struct {
+	Header    btcspv.BitcoinHeader `json:"header"`
+	Chainwork btcspv.Uint256       `json:"chainwork"`
+}
+
+// entry is a stored header and the state needed to validate its children
+type entry struct {
+	header    btcspv.BitcoinHeader
+	anchor    btcspv.ChainAnchor
+	chainwork btcspv.Uint256
+	children  int
+}
+
+// Relay stores every valid header that links to its genesis, and tracks the
+// best chain. Like the on-chain relay, adding headers never moves the tip.
+// The tip only moves when MarkNewHeaviest is called. A Relay is safe for
+// concurrent use.
+type Relay struct {
+	mu  sync.RWMutex
+	net *btcspv.NetParams
+
+	headers                 map[Hash256Digest]*entry
+	genesis                 Hash256Digest
+	bestKnownDigest         Hash256Digest
+	lastReorgCommonAncestor Hash256Digest
+
+	// chain holds the best chain digests, indexed by height - genesis height
+	chain []Hash256Digest
+}
+
+// NewRelay instantiates a Relay from a trusted genesis header on a network
+// epochStart is the timestamp of the first header in the genesis header's
+// difficulty epoch. It is needed to validate the next retarget.
+func NewRelay(genesis btcspv.BitcoinHeader, epochStart uint, net *btcspv.NetParams) *Relay {
+	e := &entry{
+		header:    genesis,
+		anchor:    btcspv.AnchorFromHeader(genesis, epochStart),
+		chainwork: btcspv.CalculateWork(btcspv.ExtractTarget(genesis.Raw)),
+	}
+
+	return &Relay{
+		net:                     net,
+		headers:                 map[Hash256Digest]*entry{genesis.Hash: e},
+		genesis:                 genesis.Hash,
+		bestKnownDigest:         genesis.Hash,
+		lastReorgCommonAncestor: genesis.Hash,
+		chain:                   []Hash256Digest{genesis.Hash},
+	}
+}
+
+// nextAnchor returns the anchor for validating the children of header
+// Within an epoch, the epoch target is inherited from the parent, as header
+// may be a min-difficulty header.
+func (r *Relay) nextAnchor(parent btcspv.ChainAnchor, header btcspv.BitcoinHeader) btcspv.ChainAnchor {
+	if header.Height%r.net.RetargetInterval == 0 {
+		return btcspv.AnchorFromHeader(header, btcspv.ExtractTimestamp(header.Raw))
+	}
+	anchor := btcspv.AnchorFromHeader(header, parent.EpochStart)
+	anchor.EpochTarget = parent.EpochTarget
+	return anchor
+}
+
+// AddHeaders stores a chain of headers extending a known anchor
+// The headers are checked with ValidateHeaderChainRetarget, so they may cross
+// a difficulty epoch boundary. Headers that are already known are skipped.
+func (r *Relay) AddHeaders(anchor Hash256Digest, headers []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parent, ok := r.headers[anchor]
+	if !ok {
+		return ErrUnknownHeader
+	}
+
+	_, err := btcspv.ValidateHeaderChainRetarget(parent.anchor, headers, r.net)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(headers)/80; i++ {
+		start := i * 80
+		end := start + 80
+		raw, _ := btcspv.NewRawHeader(headers[start:end:end])
+		header := btcspv.HeaderFromRaw(raw, parent.header.Height+1)
+
+		current, ok := r.headers[header.Hash]
+		if !ok {
+			current = &entry{
+				header:    header,
+				anchor:    r.nextAnchor(parent.anchor, header),
+				chainwork: parent.chainwork.Add(btcspv.CalculateWork(btcspv.ExtractTarget(raw))),
+			}
+			r.headers[header.Hash] = current
+			parent.children++
+		}
+		parent = current
+	}
+
+	return nil
+}
+
+// MarkNewHeaviest moves the tip from currentBest to newBest
+// ancestor must be the most recent common ancestor of both, found within limit
+// headers, and newBest must be heavier than currentBest according to
+// HeaviestFromAncestor.
+func (r *Relay) MarkNewHeaviest(ancestor, currentBest, newBest Hash256Digest, limit uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if currentBest != r.bestKnownDigest {
+		return ErrNotBestKnown
+	}
+	if _, ok := r.headers[newBest]; !ok {
+		return ErrUnknownHeader
+	}
+	if !r.isMostRecentAncestor(ancestor, currentBest, newBest, limit) {
+		return ErrNotMostRecentAncestor
+	}
+
+	heaviest, err := r.heaviestFromAncestor(ancestor, currentBest, newBest)
+	if err != nil {
+		return err
+	}
+	if heaviest != newBest {
+		return ErrNotHeavier
+	}
+
+	r.bestKnownDigest = newBest
+	r.lastReorgCommonAncestor = ancestor
+	r.reorgChain(newBest)
+	return nil
+}
+
+// reorgChain rewrites the best chain index to end at tip
+func (r *Relay) reorgChain(tip Hash256Digest) {
+	genesisHeight := r.headers[r.genesis].header.Height
+
+	var added []Hash256Digest
+	current := tip
+	for {
+		offset := int(r.headers[current].header.Height - genesisHeight)
+		if offset < len(r.chain) && r.chain[offset] == current {
+			r.chain = r.chain[:offset+1]
+			break
+		}
+		added = append(added, current)
+		current = r.headers[current].header.PrevHash
+	}
+
+	for i := len(added) - 1; i >= 0; i-- {
+		r.chain = append(r.chain, added[i])
+	}
+}
+
+// prev returns the parent of a known header, or the zero digest
+func (r *Relay) prev(digest Hash256Digest) Hash256Digest {
+	e, ok := r.headers[digest]
+	if !ok || digest == r.genesis {
+		return Hash256Digest{}
+	}
+	return e.header.PrevHash
+}
+
+// IsAncestor checks whether ancestor is within limit headers of descendant
+// A header is its own ancestor. As on-chain, a limit of 0 always fails.
+func (r *Relay) IsAncestor(ancestor, descendant Hash256Digest, limit uint) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	current := descendant
+	for i := uint(0); i < limit; i++ {
+		if current == ancestor {
+			return true
+		}
+		current = r.prev(current)
+	}
+	return false
+}
+
+// IsMostRecentAncestor checks whether ancestor is the most recent common
+// ancestor of left and right, found within limit headers of each
+func (r *Relay) IsMostRecentAncestor(ancestor, left, right Hash256Digest, limit uint) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.isMostRecentAncestor(ancestor, left, right, limit)
+}
+
+func (r *Relay) isMostRecentAncestor(ancestor, left, right Hash256Digest, limit uint) bool {
+	if ancestor == left && ancestor == right {
+		return true
+	}
+
+	leftCurrent, leftPrev := left, left
+	rightCurrent, rightPrev := right, right
+
+	for i := uint(0); i < limit; i++ {
+		if leftPrev != ancestor {
+			leftCurrent = leftPrev
+			leftPrev = r.prev(leftPrev)
+		}
+		if rightPrev != ancestor {
+			rightCurrent = rightPrev
+			rightPrev = r.prev(rightPrev)
+		}
+	}
+
+	// If the children are the same, they are a more recent common ancestor
+	if leftCurrent == rightCurrent {
+		return false
+	}
+	return leftPrev == ancestor && rightPrev == ancestor
+}
+
+// HeaviestFromAncestor decides which of two descendants of ancestor is heavier
+// This is the on-chain rule, not a chainwork comparison. A descendant that
+// reaches the next difficulty epoch beats one that does not. Within the
+// ancestor's epoch the higher header wins. Past it, each side's height into
+// its own epoch is weighted by its header's work. Work orders headers as the
+// on-chain difficulty does, but is nonzero for headers easier than difficulty
+// 1, e.g. on regtest. Ties go to left.
+func (r *Relay) HeaviestFromAncestor(ancestor, left, right Hash256Digest) (Hash256Digest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.heaviestFromAncestor(ancestor, left, right)
+}
+
+func (r *Relay) heaviestFromAncestor(ancestor, left, right Hash256Digest) (Hash256Digest, error) {
+	ancestorEntry, okAncestor := r.headers[ancestor]
+	leftEntry, okLeft := r.headers[left]
+	rightEntry, okRight := r.headers[right]
+	if !okAncestor || !okLeft || !okRight {
+		return Hash256Digest{}, ErrUnknownHeader
+	}
+
+	ancestorHeight := ancestorEntry.header.Height
+	leftHeight := leftEntry.header.Height
+	rightHeight := rightEntry.header.Height
+	if leftHeight < ancestorHeight || rightHeight < ancestorHeight {
+		return Hash256Digest{}, ErrBelowAncestor
+	}
+
+	interval := r.net.RetargetInterval
+	nextPeriodStart := ancestorHeight + interval - ancestorHeight%interval
+	leftInPeriod := leftHeight < nextPeriodStart
+	rightInPeriod := rightHeight < nextPeriodStart
+
+	switch {
+	case !leftInPeriod && rightInPeriod:
+		return left, nil
+	case leftInPeriod && !rightInPeriod:
+		return right, nil
+	case leftInPeriod && rightInPeriod:
+		if leftHeight >= rightHeight {
+			return left, nil
+		}
+		return right, nil
+	}
+
+	leftWeight := btcspv.NewUint256(uint64(leftHeight % interval)).Mul(btcspv.CalculateWork(btcspv.ExtractTarget(leftEntry.header.Raw)))
+	rightWeight := btcspv.NewUint256(uint64(rightHeight % interval)).Mul(btcspv.CalculateWork(btcspv.ExtractTarget(rightEntry.header.Raw)))
+	if leftWeight.LT(rightWeight) {
+		return right, nil
+	}
+	return left, nil
+}
+
+// Tip returns the best known header
+func (r *Relay) Tip() btcspv.BitcoinHeader {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.headers[r.bestKnownDigest].header
+}
+
+// LastReorgCommonAncestor returns the ancestor passed to the last successful
+// MarkNewHeaviest, or the genesis digest
+func (r *Relay) LastReorgCommonAncestor() Hash256Digest {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lastReorgCommonAncestor
+}
+
+// Header returns a known header by digest, whether or not it is in the best chain
+func (r *Relay) Header(digest Hash256Digest) (btcspv.BitcoinHeader, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.headers[digest]
+	if !ok {
+		return btcspv.BitcoinHeader{}, ErrUnknownHeader
+	}
+	return e.header, nil
+}
+
+// HeaderAt returns the header at a height in the best chain
+func (r *Relay) HeaderAt(height uint32) (btcspv.BitcoinHeader, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	genesisHeight := r.headers[r.genesis].header.Height
+	if height < genesisHeight || uint64(height-genesisHeight) >= uint64(len(r.chain)) {
+		return btcspv.BitcoinHeader{}, ErrHeightOutOfRange
+	}
+	return r.headers[r.chain[height-genesisHeight]].header, nil
+}
+
+// Chainwork returns the work accumulated from the genesis header up to and
+// including a known header
+func (r *Relay) Chainwork(digest Hash256Digest) (btcspv.Uint256, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.headers[digest]
+	if !ok {
+		return btcspv.Uint256{}, ErrUnknownHeader
+	}
+	return e.chainwork, nil
+}
+
+// Confirmations returns the number of best chain headers from a known header
+// to the tip, including both. Headers off the best chain have 0 confirmations.
+func (r *Relay) Confirmations(digest Hash256Digest) (uint32, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.headers[digest]
+	if !ok {
+		return 0, ErrUnknownHeader
+	}
+
+	genesisHeight := r.headers[r.genesis].header.Height
+	offset := int(e.header.Height - genesisHeight)
+	if offset >= len(r.chain) || r.chain[offset] != digest {
+		return 0, nil
+	}
+	return uint32(len(r.chain) - offset), nil
+}
+
+// Tips returns every known header without known children, heaviest first
+// The best known header is not necessarily the first, as the tip only moves
+// on MarkNewHeaviest.
+func (r *Relay) Tips() []StoredHeader {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tips := []StoredHeader{}
+	for _, e := range r.headers {
+		if e.children == 0 {
+			tips = append(tips, StoredHeader{e.header, e.chainwork})
+		}
+	}
+
+	sort.Slice(tips, func(i, j int) bool {
+		cmp := tips[i].Chainwork.Cmp(tips[j].Chainwork)
+		if cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(tips[i].Header.Hash[:], tips[j].Header.Hash[:]) < 0
+	})
+	return tips
+}
@@ -0,0 +1,237 @@
+package relay_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	btcspv "github.com/summa-tx/bitcoin-spv/golang/btcspv"
+	"github.com/summa-tx/bitcoin-spv/golang/relay"
+)
+
+// regtestBits is the regtest pow limit, low enough to mine in tests
+const regtestBits = 0x207fffff
+
+// genesisHeight is mid-epoch, so test chains never reach a retarget
+const genesisHeight = 1000
+
+// mineHeader builds a header on prev that meets regtestBits
+// seed makes sibling headers distinct
+func mineHeader(prev btcspv.Hash256Digest, timestamp uint32, seed byte) btcspv.RawHeader {
+	var raw btcspv.RawHeader
+	binary.LittleEndian.PutUint32(raw[0:4], 2)
+	copy(raw[4:36], prev[:])
+	raw[36] = seed
+	binary.LittleEndian.PutUint32(raw[68:72], timestamp)
+	binary.LittleEndian.PutUint32(raw[72:76], regtestBits)
+
+	target := btcspv.ExtractTarget(raw)
+	for nonce := uint32(0); ; nonce++ {
+		binary.LittleEndian.PutUint32(raw[76:80], nonce)
+		if btcspv.ValidateHeaderWork(btcspv.Hash256(raw[:]), target) {
+			return raw
+		}
+	}
+}
+
+// mineChain mines n headers on prev, returning the concatenated headers and
+// their digests
+func mineChain(prev btcspv.Hash256Digest, n int, seed byte) ([]byte, []btcspv.Hash256Digest) {
+	headers := []byte{}
+	digests := []btcspv.Hash256Digest{}
+	for i := 0; i < n; i++ {
+		raw := mineHeader(prev, 1600000000+uint32(i)*600, seed)
+		prev = btcspv.Hash256(raw[:])
+		headers = append(headers, raw[:]...)
+		digests = append(digests, prev)
+	}
+	return headers, digests
+}
+
+type RelaySuite struct {
+	suite.Suite
+	Genesis btcspv.BitcoinHeader
+	Relay   *relay.Relay
+}
+
+func TestRelay(t *testing.T) {
+	suite.Run(t, new(RelaySuite))
+}
+
+func (suite *RelaySuite) SetupTest() {
+	raw := mineHeader(btcspv.Hash256Digest{1}, 1599999400, 0)
+	suite.Genesis = btcspv.HeaderFromRaw(raw, genesisHeight)
+	suite.Relay = relay.NewRelay(suite.Genesis, 1599000000, &btcspv.RegTestParams)
+}
+
+func (suite *RelaySuite) TestNewRelay() {
+	r := suite.Relay
+	suite.Equal(suite.Genesis, r.Tip())
+	suite.Equal(suite.Genesis.Hash, r.LastReorgCommonAncestor())
+
+	header, err := r.HeaderAt(genesisHeight)
+	suite.Nil(err)
+	suite.Equal(suite.Genesis, header)
+
+	confs, err := r.Confirmations(suite.Genesis.Hash)
+	suite.Nil(err)
+	suite.Equal(uint32(1), confs)
+
+	work, err := r.Chainwork(suite.Genesis.Hash)
+	suite.Nil(err)
+	suite.Equal(btcspv.NewUint256(2), work)
+
+	_, err = r.HeaderAt(genesisHeight - 1)
+	suite.Equal(relay.ErrHeightOutOfRange, err)
+	_, err = r.HeaderAt(genesisHeight + 1)
+	suite.Equal(relay.ErrHeightOutOfRange, err)
+}
+
+func (suite *RelaySuite) TestAddHeaders() {
+	r := suite.Relay
+	headers, digests := mineChain(suite.Genesis.Hash, 5, 1)
+
+	suite.Nil(r.AddHeaders(suite.Genesis.Hash, headers))
+
+	// Adding headers does not move the tip
+	suite.Equal(suite.Genesis, r.Tip())
+	confs, err := r.Confirmations(digests[4])
+	suite.Nil(err)
+	suite.Equal(uint32(0), confs)
+
+	header, err := r.Header(digests[2])
+	suite.Nil(err)
+	suite.Equal(uint32(genesisHeight+3), header.Height)
+
+	work, err := r.Chainwork(digests[4])
+	suite.Nil(err)
+	suite.Equal(btcspv.NewUint256(12), work)
+
+	// Known headers are skipped
+	suite.Nil(r.AddHeaders(digests[1], headers[160:]))
+	suite.Len(r.Tips(), 1)
+
+	err = r.AddHeaders(btcspv.Hash256Digest{}, headers)
+	suite.Equal(relay.ErrUnknownHeader, err)
+
+	// Headers must link to the anchor
+	err = r.AddHeaders(suite.Genesis.Hash, headers[80:])
+	suite.True(errors.Is(err, btcspv.ErrInvalidChain))
+
+	err = r.AddHeaders(suite.Genesis.Hash, headers[1:])
+	suite.True(errors.Is(err, btcspv.ErrBadLength))
+
+	// Headers must not change difficulty mid-epoch
+	changed := mineHeader(suite.Genesis.Hash, 1600000000, 9)
+	binary.LittleEndian.PutUint32(changed[72:76], 0x207ffffe)
+	err = r.AddHeaders(suite.Genesis.Hash, changed[:])
+	suite.True(errors.Is(err, btcspv.ErrUnexpectedDifficultyChange))
+}
+
+func (suite *RelaySuite) TestMarkNewHeaviest() {
+	r := suite.Relay
+	g := suite.Genesis.Hash
+	headers, digests := mineChain(g, 5, 1)
+	suite.Nil(r.AddHeaders(g, headers))
+
+	suite.Equal(relay.ErrNotBestKnown, r.MarkNewHeaviest(g, digests[0], digests[4], 10))
+	suite.Equal(relay.ErrUnknownHeader, r.MarkNewHeaviest(g, g, btcspv.Hash256Digest{}, 10))
+	suite.Equal(relay.ErrNotMostRecentAncestor, r.MarkNewHeaviest(g, g, digests[4], 3))
+	suite.Equal(relay.ErrNotMostRecentAncestor, r.MarkNewHeaviest(digests[0], g, digests[4], 10))
+
+	suite.Nil(r.MarkNewHeaviest(g, g, digests[4], 10))
+	suite.Equal(digests[4], r.Tip().Hash)
+	suite.Equal(g, r.LastReorgCommonAncestor())
+
+	header, err := r.HeaderAt(genesisHeight + 2)
+	suite.Nil(err)
+	suite.Equal(digests[1], header.Hash)
+
+	confs, err := r.Confirmations(g)
+	suite.Nil(err)
+	suite.Equal(uint32(6), confs)
+
+	// A shorter fork from digests[1] is not heavier
+	fork, forkDigests := mineChain(digests[1], 2, 2)
+	suite.Nil(r.AddHeaders(digests[1], fork))
+	suite.Len(r.Tips(), 2)
+	suite.Equal(relay.ErrNotHeavier, r.MarkNewHeaviest(digests[1], digests[4], forkDigests[1], 10))
+
+	// An equal-height fork is not heavier either
+	fork, forkDigests = mineChain(digests[1], 3, 3)
+	suite.Nil(r.AddHeaders(digests[1], fork))
+	suite.Equal(relay.ErrNotHeavier, r.MarkNewHeaviest(digests[1], digests[4], forkDigests[2], 10))
+
+	// A longer fork reorgs the best chain
+	fork, forkDigests = mineChain(digests[1], 4, 4)
+	suite.Nil(r.AddHeaders(digests[1], fork))
+	suite.Nil(r.MarkNewHeaviest(digests[1], digests[4], forkDigests[3], 10))
+	suite.Equal(forkDigests[3], r.Tip().Hash)
+	suite.Equal(digests[1], r.LastReorgCommonAncestor())
+
+	header, err = r.HeaderAt(genesisHeight + 3)
+	suite.Nil(err)
+	suite.Equal(forkDigests[0], header.Hash)
+	_, err = r.HeaderAt(genesisHeight + 7)
+	suite.Equal(relay.ErrHeightOutOfRange, err)
+
+	confs, err = r.Confirmations(digests[4])
+	suite.Nil(err)
+	suite.Equal(uint32(0), confs)
+	confs, err = r.Confirmations(forkDigests[0])
+	suite.Nil(err)
+	suite.Equal(uint32(4), confs)
+
+	tips := r.Tips()
+	suite.Len(tips, 4)
+	suite.Equal(forkDigests[3], tips[0].Header.Hash)
+	suite.Equal(btcspv.NewUint256(14), tips[0].Chainwork)
+}
+
+func (suite *RelaySuite) TestIsAncestor() {
+	r := suite.Relay
+	g := suite.Genesis.Hash
+	headers, digests := mineChain(g, 5, 1)
+	suite.Nil(r.AddHeaders(g, headers))
+	fork, forkDigests := mineChain(digests[1], 2, 2)
+	suite.Nil(r.AddHeaders(digests[1], fork))
+
+	suite.True(r.IsAncestor(g, digests[4], 6))
+	suite.False(r.IsAncestor(g, digests[4], 5))
+	suite.True(r.IsAncestor(digests[4], digests[4], 1))
+	suite.False(r.IsAncestor(digests[4], digests[4], 0))
+	suite.False(r.IsAncestor(digests[4], g, 10))
+	suite.False(r.IsAncestor(digests[2], forkDigests[1], 10))
+	suite.True(r.IsAncestor(digests[1], forkDigests[1], 10))
+
+	suite.True(r.IsMostRecentAncestor(digests[1], digests[4], forkDigests[1], 10))
+	suite.False(r.IsMostRecentAncestor(digests[0], digests[4], forkDigests[1], 10))
+	suite.False(r.IsMostRecentAncestor(digests[1], digests[4], forkDigests[1], 2))
+	suite.True(r.IsMostRecentAncestor(g, g, g, 0))
+
+	heaviest, err := r.HeaviestFromAncestor(digests[1], digests[4], forkDigests[1])
+	suite.Nil(err)
+	suite.Equal(digests[4], heaviest)
+	heaviest, err = r.HeaviestFromAncestor(digests[1], forkDigests[1], digests[3])
+	suite.Nil(err)
+	suite.Equal(forkDigests[1], heaviest)
+
+	_, err = r.HeaviestFromAncestor(digests[2], digests[4], digests[1])
+	suite.Equal(relay.ErrBelowAncestor, err)
+
+	// Past the ancestor's epoch, heights into the next epoch are weighted by
+	// work, which is nonzero on regtest
+	raw := mineHeader(btcspv.Hash256Digest{2}, 1599999400, 0)
+	boundary := relay.NewRelay(btcspv.HeaderFromRaw(raw, 2014), 1599000000, &btcspv.RegTestParams)
+	b := btcspv.Hash256(raw[:])
+	long, longDigests := mineChain(b, 5, 1)
+	suite.Nil(boundary.AddHeaders(b, long))
+	short, shortDigests := mineChain(b, 3, 2)
+	suite.Nil(boundary.AddHeaders(b, short))
+	heaviest, err = boundary.HeaviestFromAncestor(b, shortDigests[2], longDigests[4])
+	suite.Nil(err)
+	suite.Equal(longDigests[4], heaviest)
+	_, err = r.HeaviestFromAncestor(btcspv.Hash256Digest{}, digests[4], digests[1])
+	suite.Equal(relay.ErrUnknownHeader, err)
+}
@@ -13,7 +13,11 @@ export const OUTPUT_TYPES = {
   OP_RETURN: BigInt(3),
   PKH: BigInt(4),
   SH: BigInt(5),
-  NONSTANDARD: BigInt(6)
+  NONSTANDARD: BigInt(6),
+  TR: BigInt(7),
+  PK: BigInt(8),
+  MULTISIG: BigInt(9),
+  WITNESS_UNKNOWN: BigInt(10)
 };
 
 /**
@@ -9,7 +9,11 @@ module.exports = {
     OP_RETURN: new BN(3, 10),
     PKH: new BN(4, 10),
     SH: new BN(5, 10),
-    NONSTANDARD: new BN(6, 10)
+    NONSTANDARD: new BN(6, 10),
+    TR: new BN(7, 10),
+    PK: new BN(8, 10),
+    MULTISIG: new BN(9, 10),
+    WITNESS_UNKNOWN: new BN(10, 10)
   },
 
   INPUT_TYPES: {